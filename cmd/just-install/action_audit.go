@@ -25,6 +25,7 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/just-install/just-install/pkg/fetch"
+	"github.com/just-install/just-install/pkg/language"
 )
 
 func handleAuditAction(c *cli.Context) error {
@@ -76,9 +77,10 @@ func handleAuditAction(c *cli.Context) error {
 		return ret
 	}
 
+	// Audits are meant to be reproducible, hence the OS locale is not taken into account here.
 	lang := c.String("lang")
 	if lang == "" {
-		lang = "en-US"
+		lang = language.Default
 	}
 
 	registry, err := loadRegistry(c, c.Bool("force"), !c.Bool("noprogress"))
@@ -137,22 +139,35 @@ func handleAuditAction(c *cli.Context) error {
 			continue
 		}
 
-		if entry.Installer.X86 != "" {
-			installerURL, err := expandString(entry.Installer.X86, map[string]string{"version": entry.Version, "lang": lang})
-			if err != nil {
-				panic(err)
+		// Check every declared language, since any of them could be picked at install time.
+		languages := entry.Languages
+		if len(languages) == 0 {
+			languages = []string{lang}
+		}
+
+		for _, l := range languages {
+			suffix := ""
+			if len(entry.Languages) > 0 {
+				suffix = ", " + l
 			}
 
-			workerQueue <- workItem{name + " (x86)", installerURL}
-		}
+			if entry.Installer.X86 != "" {
+				installerURL, err := expandString(entry.Installer.X86, map[string]string{"version": entry.Version, "lang": l})
+				if err != nil {
+					panic(err)
+				}
 
-		if entry.Installer.X86_64 != "" {
-			installerURL, err := expandString(entry.Installer.X86_64, map[string]string{"version": entry.Version, "lang": lang})
-			if err != nil {
-				panic(err)
+				workerQueue <- workItem{name + " (x86" + suffix + ")", installerURL}
 			}
 
-			workerQueue <- workItem{name + " (x86_64)", installerURL}
+			if entry.Installer.X86_64 != "" {
+				installerURL, err := expandString(entry.Installer.X86_64, map[string]string{"version": entry.Version, "lang": l})
+				if err != nil {
+					panic(err)
+				}
+
+				workerQueue <- workItem{name + " (x86_64" + suffix + ")", installerURL}
+			}
 		}
 	}
 
@@ -0,0 +1,65 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/installdb"
+)
+
+func handleHoldAction(c *cli.Context) error {
+	return setHeld(c, true)
+}
+
+func handleUnholdAction(c *cli.Context) error {
+	return setHeld(c, false)
+}
+
+// setHeld holds or releases the installed packages given on the command line.
+func setHeld(c *cli.Context, held bool) error {
+	if c.Args().Len() < 1 {
+		return errors.New("expected at least one package name")
+	}
+
+	store, err := installdb.DefaultStore()
+	if err != nil {
+		return err
+	}
+
+	return store.Update(func(db *installdb.Database) error {
+		for _, name := range c.Args().Slice() {
+			record, ok := db.Lookup(name)
+			if !ok {
+				return fmt.Errorf("package %v is not installed", name)
+			}
+
+			record.Held = held
+
+			if held {
+				log.Printf("holding %v at version %v", record.Name, record.Version)
+			} else {
+				log.Printf("releasing %v", record.Name)
+			}
+		}
+
+		return nil
+	})
+}
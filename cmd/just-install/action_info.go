@@ -0,0 +1,75 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/language"
+)
+
+func handleInfoAction(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return errors.New("expected exactly one package name")
+	}
+
+	registry, err := loadRegistry(c, false, !c.Bool("noprogress"))
+	if err != nil {
+		return err
+	}
+
+	match, ok := resolvePackage(registry, c.Args().First())
+	if !ok {
+		return fmt.Errorf("unknown package: %v", c.Args().First())
+	}
+
+	entry := match.Package
+
+	fmt.Printf("%-12v %v\n", "Name:", match.Name)
+	fmt.Printf("%-12v %v\n", "Version:", entry.Version)
+	fmt.Printf("%-12v %v\n", "Kind:", entry.Installer.Kind)
+
+	if entry.Installer.X86 != "" {
+		fmt.Printf("%-12v %v\n", "x86:", entry.Installer.X86)
+	}
+
+	if entry.Installer.X86_64 != "" {
+		fmt.Printf("%-12v %v\n", "x86_64:", entry.Installer.X86_64)
+	}
+
+	if len(entry.Aliases) > 0 {
+		fmt.Printf("%-12v %v\n", "Aliases:", strings.Join(entry.Aliases, ", "))
+	}
+
+	if notice := deprecationNotice(entry); notice != "" {
+		fmt.Printf("%-12v %v\n", "Deprecated:", notice)
+	}
+
+	if len(entry.Languages) > 0 {
+		selected, _ := language.Select(language.Preferences(c.String("lang")), entry.Languages)
+
+		fmt.Printf("%-12v %v\n", "Languages:", strings.Join(entry.Languages, ", "))
+		fmt.Printf("%-12v %v\n", "Selected:", selected)
+	} else {
+		fmt.Printf("%-12v %v\n", "Languages:", "not declared")
+	}
+
+	return nil
+}
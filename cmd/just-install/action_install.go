@@ -21,18 +21,22 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
+	"time"
 
-	"github.com/gotopkg/mslnk/pkg/mslnk"
 	"github.com/ungerik/go-dry"
 	"github.com/urfave/cli/v2"
 
 	"github.com/just-install/just-install/pkg/cmd"
 	"github.com/just-install/just-install/pkg/fetch"
+	"github.com/just-install/just-install/pkg/installdb"
 	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/language"
 	"github.com/just-install/just-install/pkg/paths"
 	"github.com/just-install/just-install/pkg/platform"
 	"github.com/just-install/just-install/pkg/registry4"
@@ -40,8 +44,8 @@ import (
 )
 
 var (
-	shimsPath = os.ExpandEnv("${SystemDrive}\\Shims")
-	startMenu = os.ExpandEnv("${ProgramData}\\Microsoft\\Windows\\Start Menu\\Programs")
+	langTemplateRegexp = regexp.MustCompile(`{{\s*\.lang\s*}}`)
+	shimsPath          = os.ExpandEnv("${SystemDrive}\\Shims")
 )
 
 func handleInstall(c *cli.Context) error {
@@ -63,61 +67,91 @@ func handleInstall(c *cli.Context) error {
 		return err
 	}
 
-	lang := c.String("lang")
-	if lang == "" {
-		lang = "en-US"
+	scope := installer.Scope(c.String("scope"))
+	if !scope.IsValid() {
+		return fmt.Errorf("unknown scope: %v (expected \"user\" or \"machine\")", scope)
+	}
+
+	settings := &installSettings{
+		Arch:        arch,
+		Languages:   language.Preferences(c.String("lang")),
+		Scope:       scope,
+		IgnoreCache: ignoreCache,
+		Progress:    progress,
+		Timeout:     c.Duration("timeout"),
+		Host:        hostFromContext(c),
 	}
 
 	// Install packages
 	hasErrors := false
+	var installed, rebootRequired, retryable, failed []string
 
-	for _, pkg := range c.Args().Slice() {
-		entry, ok := registry.Packages[pkg]
+	for _, name := range c.Args().Slice() {
+		match, ok := resolvePackage(registry, name)
 		if !ok {
-			log.Println("WARNING: unknown package", pkg)
 			continue
 		}
 
-		options, err := entry.Installer.OptionsForArch(arch)
-		if err != nil {
-			return err
-		}
+		pkg := match.Name
+		entry := match.Package
 
 		if onlyShims {
-			if err := createShims(options); err != nil {
+			options, err := entry.Installer.OptionsForArch(arch)
+			if err != nil {
 				return err
 			}
 
-			continue
-		}
+			if _, err := createShims(settings.Host, options); err != nil {
+				return err
+			}
 
-		installerPath, err := fetchInstaller(entry, arch, lang, ignoreCache, progress)
-		if err != nil {
-			log.Printf("error downloading %v: %v", pkg, err)
-			hasErrors = true
 			continue
 		}
 
 		if onlyDownload {
+			if _, _, err := fetchInstaller(entry, settings); err != nil {
+				log.Printf("error downloading %v: %v", pkg, err)
+				hasErrors = true
+			}
+
 			continue
 		}
 
-		installerPath, err = maybeExtractContainer(installerPath, options)
+		status, err := installPackage(pkg, entry, settings, nil)
 		if err != nil {
-			return err
-		}
-
-		if err := install(installerPath, entry.Installer.Kind, options); err != nil {
 			log.Printf("error installing %v: %v", pkg, err)
 			hasErrors = true
-			continue
 		}
 
-		if exeproxyExists() {
-			createShims(options)
+		switch status {
+		case installer.ExitSuccess:
+			installed = append(installed, pkg)
+		case installer.ExitSuccessRebootRequired:
+			installed = append(installed, pkg)
+			rebootRequired = append(rebootRequired, pkg)
+		case installer.ExitRetryable:
+			retryable = append(retryable, pkg)
+		default:
+			failed = append(failed, pkg)
 		}
 	}
 
+	if len(installed)+len(retryable)+len(failed) > 0 {
+		fmt.Println()
+		if settings.Host.DryRun {
+			printSummary("would be installed", installed)
+		} else {
+			printSummary("installed", installed)
+		}
+
+		printSummary("failed, but can be retried later", retryable)
+		printSummary("failed", failed)
+	}
+
+	if len(rebootRequired) > 0 {
+		log.Printf("a reboot is required to complete the installation of: %v", strings.Join(rebootRequired, ", "))
+	}
+
 	if hasErrors {
 		return errors.New("encountered errors installing packages (see the log for details)")
 	}
@@ -125,6 +159,147 @@ func handleInstall(c *cli.Context) error {
 	return nil
 }
 
+// installSettings are the settings shared by the packages installed in one go.
+type installSettings struct {
+	Arch        string
+	Languages   []string // Preferred languages, most preferred first
+	Scope       installer.Scope
+	IgnoreCache bool // Download installers again even if they are in the cache
+	Progress    bool
+	Timeout     time.Duration // Default installer timeout, zero for none
+	Host        *host
+}
+
+// installPackage downloads and installs the given package, recording it in the installed-package
+// database. When upgrading, previous is the record of the installation being replaced. The returned
+// status tells whether installation failed for good or can be retried, and whether a reboot is
+// required to complete it.
+func installPackage(name string, entry *registry4.Package, settings *installSettings, previous *installdb.Record) (installer.ExitStatus, error) {
+	options, err := entry.Installer.OptionsForArch(settings.Arch)
+	if err != nil {
+		return installer.ExitFailure, err
+	}
+
+	installerPath, lang, err := fetchInstaller(entry, settings)
+	if err != nil {
+		return installer.ExitFailure, fmt.Errorf("could not download installer: %w", err)
+	}
+
+	h := settings.Host
+
+	// Dry runs don't download installers that weren't already
+	var installerHash string
+	if !h.DryRun || dry.FileExists(installerPath) {
+		installerHash, err = fetch.SHA256(installerPath)
+		if err != nil {
+			return installer.ExitFailure, fmt.Errorf("could not compute checksum of %v: %w", installerPath, err)
+		}
+	}
+
+	installerPath, err = maybeExtractContainer(h, installerPath, options)
+	if err != nil {
+		return installer.ExitFailure, err
+	}
+
+	timeout, err := installerTimeout(options, settings.Timeout)
+	if err != nil {
+		return installer.ExitFailure, err
+	}
+
+	if options != nil {
+		if err := runHooks(h, name, "pre-install", options.PreInstall, installerPath, timeout); err != nil {
+			return installer.ExitFailure, err
+		}
+	}
+
+	runOptions, err := newRunOptions(h, name, "install", timeout)
+	if err != nil {
+		return installer.ExitFailure, err
+	}
+
+	var backup *upgradeBackup
+	if previous != nil {
+		if backup, err = prepareUpgrade(h, previous, entry.Installer.Kind); err != nil {
+			return installer.ExitFailure, err
+		}
+	}
+
+	artifacts, err := install(h, installerPath, entry.Installer.Kind, options, settings.Scope, runOptions)
+	if err != nil {
+		if backup != nil {
+			backup.restore()
+		}
+
+		return exitStatusOf(err), err
+	}
+
+	if backup != nil {
+		backup.discard()
+	}
+
+	var shims []string
+	if exeproxyExists() || h.DryRun {
+		shims, _ = createShims(h, options)
+	}
+
+	shortcuts, err := createShortcuts(h, options, settings.Scope)
+	if err != nil {
+		log.Printf("WARNING: %v was installed but its shortcuts could not all be created: %v", name, err)
+	}
+
+	if previous != nil {
+		removeStaleShortcuts(h, previous, shortcuts)
+
+		if err := revertEnvironment(h, previous); err != nil {
+			log.Printf("WARNING: could not undo the environment changes of the previous version: %v", err)
+		}
+	}
+
+	pathEntries, environmentChanges, err := applyEnvironment(h, options, settings.Scope)
+	if err != nil {
+		log.Printf("WARNING: %v was installed but its environment could not be set up: %v", name, err)
+	}
+
+	// The package is recorded even if post-install commands fail, since it's there to be uninstalled
+	var hookErr error
+	if options != nil {
+		hookErr = runHooks(h, name, "post-install", options.PostInstall, installerPath, timeout)
+	}
+
+	record := &installdb.Record{
+		Name:            name,
+		Version:         entry.Version,
+		Arch:            settings.Arch,
+		Language:        lang,
+		Scope:           string(settings.Scope),
+		Kind:            entry.Installer.Kind,
+		Destination:     artifacts.Destination,
+		Shims:           shims,
+		Shortcuts:       shortcuts,
+		Path:            pathEntries,
+		Environment:     environmentChanges,
+		InstallerSHA256: installerHash,
+		InstalledAt:     time.Now().UTC(),
+	}
+
+	if options != nil && options.Uninstall != nil {
+		record.ProductCode = options.Uninstall.ProductCode
+		record.Uninstall = options.Uninstall.Arguments
+	}
+
+	if h.DryRun {
+		log.Printf("would record %v %v as installed", name, entry.Version)
+	} else if err := recordInstallation(record); err != nil {
+		log.Printf("WARNING: %v was installed but could not be recorded: %v", name, err)
+	}
+
+	if hookErr != nil {
+		return installer.ExitFailure, fmt.Errorf("installed, but %w", hookErr)
+	}
+
+	return artifacts.Status, nil
+}
+
 // getInstallArch returns the architecture selected for package installation based on the given
 // preferred architecture (e.g. given by the user via command line arguments). The given preferred
 // architecture can be empty, in which case a suitable one is automatically selected for the current
@@ -150,73 +325,131 @@ func getInstallArch(preferredArch string) (string, error) {
 	}
 }
 
-// fetchInstaller fetches the installer for the given package and returns
-func fetchInstaller(entry *registry4.Package, arch string, lang string, overwrite bool, progress bool) (string, error) {
+// fetchInstaller fetches the installer for the given package and returns its path, along with the
+// language it was downloaded in (empty if the installer is language-neutral). The installer is
+// downloaded in the first of the given languages that the package supports. Packages that don't
+// declare their languages are tried with each language in turn, as long as the download fails with
+// a "404 Not Found" status.
+func fetchInstaller(entry *registry4.Package, settings *installSettings) (string, string, error) {
+	installerURL, installerArch, err := installerURLForArch(entry, settings.Arch)
+	if err != nil {
+		return "", "", err
+	}
+
+	localized := true
+
+	candidates := settings.Languages
+	if len(entry.Languages) > 0 {
+		lang, ok := language.Select(settings.Languages, entry.Languages)
+		if !ok {
+			log.Printf("WARNING: none of the preferred languages are available, falling back to %v", lang)
+		}
+
+		candidates = []string{lang}
+	} else if !langTemplateRegexp.MatchString(installerURL) {
+		candidates = settings.Languages[:1]
+		localized = false
+	}
+
+	downloadDir := paths.TempDir()
+	if !settings.Host.DryRun {
+		if _, err := paths.TempDirCreate(); err != nil {
+			return "", "", fmt.Errorf("could not create temporary directory to download installer: %w", err)
+		}
+	}
+
+	for i, lang := range candidates {
+		expandedURL, err := expandString(installerURL, map[string]string{"version": entry.Version, "lang": lang})
+		if err != nil {
+			return "", "", fmt.Errorf("could not expand installer URL's template string: %w", err)
+		}
+
+		ret, err := settings.Host.Fetcher.Fetch(expandedURL, &fetch.Options{
+			Destination: downloadDir,
+			Overwrite:   settings.IgnoreCache,
+			Progress:    settings.Progress,
+		})
+
+		var statusErr *fetch.HTTPStatusError
+		if errors.As(err, &statusErr) && statusErr.Received == http.StatusNotFound && i < len(candidates)-1 {
+			log.Printf("installer not available in %v, trying %v", lang, candidates[i+1])
+			continue
+		} else if err != nil {
+			return "", "", err
+		}
+
+		// Checksums can only be tracked for a single language, see computeChecksums
+		if checksum, ok := entry.Installer.Checksums[installerArch]; ok && !langTemplateRegexp.MatchString(installerURL) && dry.FileExists(ret) {
+			if err := fetch.VerifySHA256(ret, checksum); err != nil {
+				// Don't let a corrupted download linger in the cache
+				os.Remove(ret)
+				return "", "", err
+			}
+		}
+
+		if !localized {
+			lang = ""
+		}
+
+		return ret, lang, nil
+	}
+
+	panic("programmer error")
+}
+
+// installerURLForArch returns the (unexpanded) URL of the installer of the given package for the
+// given architecture, along with the architecture the URL belongs to (which is different from the
+// requested one when falling back to the 32-bit installer).
+func installerURLForArch(entry *registry4.Package, arch string) (string, string, error) {
 	// Sanity check
 	if isEmptyString(entry.Installer.X86) && isEmptyString(entry.Installer.X86_64) {
-		return "", errors.New("package entry is missing both 32-bit and 64-bit installers")
+		return "", "", errors.New("package entry is missing both 32-bit and 64-bit installers")
 	}
 
 	// Pick preferred installer
-	var installerURL string
 	switch arch {
 	case "x86":
 		if isEmptyString(entry.Installer.X86) {
-			return "", errors.New("this package doesn't offer a 32-bit installer")
+			return "", "", errors.New("this package doesn't offer a 32-bit installer")
 		}
 
-		installerURL = entry.Installer.X86
+		return entry.Installer.X86, "x86", nil
 	case "x86_64":
 		if isEmptyString(entry.Installer.X86_64) {
 			// Fallback to the 32-bit installer
-			installerURL = entry.Installer.X86
-		} else {
-			installerURL = entry.Installer.X86_64
+			return entry.Installer.X86, "x86", nil
 		}
+
+		return entry.Installer.X86_64, "x86_64", nil
 	default:
 		panic("programmer error")
 	}
-
-	installerURL, err := expandString(installerURL, map[string]string{"version": entry.Version, "lang": lang})
-	if err != nil {
-		return "", fmt.Errorf("could not expand installer URL's template string: %w", err)
-	}
-
-	downloadDir, err := paths.TempDirCreate()
-	if err != nil {
-		return "", fmt.Errorf("could not create temporary directory to download installer: %w", err)
-	}
-
-	ret, err := fetch.Fetch(installerURL, &fetch.Options{
-		Destination: downloadDir,
-		Overwrite:   overwrite,
-		Progress:    progress,
-	})
-
-	return ret, err
 }
 
-func maybeExtractContainer(path string, options *registry4.Options) (string, error) {
+// maybeExtractContainer extracts the installer from the downloaded file, if the options say it's a
+// container, and returns its path. Otherwise the downloaded file is the installer.
+func maybeExtractContainer(h *host, path string, options *registry4.Options) (string, error) {
 	if options == nil || options.Container == nil {
 		return path, nil
 	}
 
-	if options.Container.Kind != "zip" {
-		return "", errors.New("only \"zip\" containers are supported")
+	// An empty kind means that the container format is detected from its contents
+	containerKind := installer.ContainerKind(options.Container.Kind)
+	if containerKind != "" && !containerKind.IsValid() {
+		return "", fmt.Errorf("unknown container kind: %v", options.Container.Kind)
 	}
 
-	tempDir, err := paths.TempDirCreate()
-	if err != nil {
-		return "", err
-	}
-
-	extractDir := filepath.Join(tempDir, filepath.Base(path)+"_extracted")
-	log.Println("extracting container", path, "to", extractDir)
-	if err := installer.ExtractZIP(path, extractDir); err != nil {
+	extractDir := filepath.Join(paths.TempDir(), filepath.Base(path)+"_extracted")
+	if err := h.FS.Extract(path, containerKind, extractDir, nil); err != nil {
 		return "", err
 	}
 
 	if strings2.IsEmpty(options.Container.Installer) {
+		// The contents of the container are unknown until it's extracted
+		if h.DryRun && !dry.FileIsDir(extractDir) {
+			return filepath.Join(extractDir, "<installer>"), nil
+		}
+
 		files, err := ioutil.ReadDir(extractDir)
 		if err != nil {
 			return "", err
@@ -232,104 +465,291 @@ func maybeExtractContainer(path string, options *registry4.Options) (string, err
 	return filepath.Join(extractDir, options.Container.Installer), nil
 }
 
-func install(path string, kind string, options *registry4.Options) error {
+// installArtifacts describes what an installation left on the machine, besides what the installer
+// itself keeps track of.
+type installArtifacts struct {
+	Destination string               // Installation directory (or file, for "copy" packages), if known
+	Status      installer.ExitStatus // Success, or success requiring a reboot
+}
+
+// install runs the installer at path, or extracts or copies it depending on its kind. Logs and output
+// of installers go where runOptions says.
+func install(h *host, path string, kind string, options *registry4.Options, scope installer.Scope, runOptions *cmd.Options) (*installArtifacts, error) {
+	ret := &installArtifacts{}
+
+	// Only proper installers can be told which scope to install for
+	if installerType := installer.InstallerType(kind); scope != "" && !installerType.IsValid() {
+		return nil, fmt.Errorf("%v packages cannot be told to install for scope %q", kind, scope)
+	}
+
 	// One-off, custom, installers
 	switch kind {
 	case "copy":
 		if options == nil {
-			return errors.New("the \"copy\" installer requires additional options")
+			return nil, errors.New("the \"copy\" installer requires additional options")
 		}
 
 		if strings2.IsEmpty(options.Destination) {
-			return errors.New("\"destination\" is missing from installer options")
+			return nil, errors.New("\"destination\" is missing from installer options")
 		}
 
 		destination, err := expandString(options.Destination, nil)
 		if err != nil {
-			return fmt.Errorf("could not expand destination string: %w", err)
+			return nil, fmt.Errorf("could not expand destination string: %w", err)
 		}
 
-		parentDir := filepath.Dir(destination)
-		log.Println("creating", parentDir)
-		if err := os.MkdirAll(parentDir, os.ModePerm); err != nil {
-			return err
+		if err := h.FS.Copy(path, destination); err != nil {
+			return nil, err
 		}
 
-		log.Println("copying to", destination)
-		return dry.FileCopy(path, destination)
+		ret.Destination = destination
+
+		return ret, nil
 	case "custom":
 		if options == nil {
-			return errors.New("the \"custom\" installer requires additional options")
+			return nil, errors.New("the \"custom\" installer requires additional options")
 		}
 
 		if len(options.Arguments) < 1 {
-			return errors.New("\"arguments\" is missing from installer options")
+			return nil, errors.New("\"arguments\" is missing from installer options")
 		}
 
 		var args []string
 		for _, v := range options.Arguments {
 			expanded, err := expandString(v, map[string]string{"installer": path})
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			args = append(args, expanded)
 		}
 
-		return cmd.Run(args...)
-	case "zip":
-		if options == nil {
-			return errors.New("the \"zip\" installer requires additional options")
-		}
+		// Custom installers don't write a log where we'd like them to
+		customOptions := *runOptions
+		customOptions.LogFile = ""
 
-		if strings2.IsEmpty(options.Destination) {
-			return errors.New("\"destination\" is missing from installer options")
+		status, err := runInstaller(h, "", &customOptions, args...)
+		if err != nil {
+			return nil, err
 		}
 
+		ret.Status = status
+
+		return ret, nil
+	}
+
+	// Archives
+	if containerKind := installer.ContainerKind(kind); containerKind.IsValid() {
+		return installArchive(h, path, containerKind, options)
+	}
+
+	// Regular installer
+	installerType := installer.InstallerType(kind)
+	if !installerType.IsValid() {
+		return nil, fmt.Errorf("unknown installer type: %v", kind)
+	}
+
+	commandOptions := &installer.CommandOptions{LogFile: runOptions.LogFile, Scope: scope}
+	if options != nil && !strings2.IsEmpty(options.Destination) {
 		destination, err := expandString(options.Destination, nil)
 		if err != nil {
-			return fmt.Errorf("could not expand destination string: %w", err)
+			return nil, fmt.Errorf("could not expand destination string: %w", err)
 		}
 
-		log.Println("extracting to", destination)
-		if err := installer.ExtractZIP(path, destination); err != nil {
-			return err
-		}
+		commandOptions.Destination = destination
+		ret.Destination = destination
+	}
 
-		for _, shortcut := range options.Shortcuts {
-			shortcutName, err := expandString(shortcut.Name, nil)
+	if options != nil {
+		for _, v := range options.ExtraArguments {
+			expanded, err := expandString(v, nil)
 			if err != nil {
-				return fmt.Errorf("could not expand shortcut name string template: %w", err)
+				return nil, err
 			}
 
-			shortcutTarget, err := expandString(shortcut.Target, nil)
-			if err != nil {
-				return fmt.Errorf("could not expand shortcut target string template: %w", err)
-			}
+			commandOptions.ExtraArguments = append(commandOptions.ExtraArguments, expanded)
+		}
+	}
+
+	installerCommand, err := installer.Command(path, installerType, commandOptions)
+	if err != nil {
+		return nil, err
+	}
 
-			shortcutLocation := filepath.Join(startMenu, shortcutName+".lnk")
+	// Some installers can't read the arguments they'd receive from exec on Windows
+	installerOptions := *runOptions
+	installerOptions.CommandLine = installer.CommandLine(installerType, installerCommand)
 
-			log.Println("creating shortcut to", shortcutTarget, "in", shortcutLocation)
-			if err := mslnk.LinkFile(shortcutTarget, shortcutLocation); err != nil {
-				return fmt.Errorf("could not create shortcut: %w", err)
-			}
+	status, err := runInstaller(h, installerType, &installerOptions, installerCommand...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.Status = status
+
+	return ret, nil
+}
+
+// exitStatusError is returned when an installer exits with a code meaning that it didn't install.
+type exitStatusError struct {
+	Status installer.ExitStatus
+	Err    error
+}
+
+func (e *exitStatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *exitStatusError) Unwrap() error {
+	return e.Err
+}
+
+// exitStatusOf returns whether the given installation error can be retried, as an exit status.
+func exitStatusOf(err error) installer.ExitStatus {
+	var statusErr *exitStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status
+	}
+
+	return installer.ExitFailure
+}
+
+// runInstaller runs an installer (or uninstaller) of the given type, interpreting its exit code. An
+// empty type stands for installers that are only known to exit with 0 on success. Installers that
+// fail with a retryable exit code are run again according to the retry policy of the host.
+func runInstaller(h *host, installerType installer.InstallerType, runOptions *cmd.Options, args ...string) (installer.ExitStatus, error) {
+	for retry := 1; ; retry++ {
+		status, err := runInstallerOnce(h.Runner, installerType, runOptions, args...)
+		if status != installer.ExitRetryable {
+			return status, err
 		}
 
-		return nil
+		if h.Retry.Retries == 0 {
+			return status, err
+		}
+
+		if retry > h.Retry.Retries {
+			return status, &exitStatusError{status, fmt.Errorf("giving up after %v retries: %w", h.Retry.Retries, err)}
+		}
+
+		reason := "retryable failure"
+		var cmdErr *cmd.Error
+		if errors.As(err, &cmdErr) {
+			reason = fmt.Sprintf("%v (exit code %v)", cmdErr.Description, cmdErr.ExitCode)
+		}
+
+		delay := h.Retry.delay(retry)
+		log.Printf("%v failed, running it again in %v (retry %v of %v): %v", args[0], delay, retry, h.Retry.Retries, reason)
+		time.Sleep(delay)
 	}
+}
 
-	// Regular installer
-	installerType := installer.InstallerType(kind)
-	if !installerType.IsValid() {
-		return fmt.Errorf("unknown installer type: %v", kind)
+// runInstallerOnce is the same as runInstaller, without retries.
+func runInstallerOnce(runner cmd.Runner, installerType installer.InstallerType, runOptions *cmd.Options, args ...string) (installer.ExitStatus, error) {
+	err := runner.Run(runOptions, args...)
+	if err == nil {
+		return installer.ExitSuccess, nil
+	}
+
+	var cmdErr *cmd.Error
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode < 0 {
+		return installer.ExitFailure, err
+	}
+
+	exitCode := installer.InterpretExitCode(installerType, cmdErr.ExitCode)
+	switch exitCode.Status {
+	case installer.ExitSuccess:
+		return exitCode.Status, nil
+	case installer.ExitSuccessRebootRequired:
+		log.Printf("%v exited with code %v: %v", args[0], cmdErr.ExitCode, exitCode.Description)
+		return exitCode.Status, nil
+	}
+
+	cmdErr.Description = exitCode.Description
+
+	return exitCode.Status, &exitStatusError{exitCode.Status, cmdErr}
+}
+
+// idleWarningTimeout is how long installers may go without any sign of activity before a warning is
+// logged.
+const idleWarningTimeout = 5 * time.Minute
+
+// newRunOptions returns the options to run installers or uninstallers of the given package, so that
+// their logs and output are kept in the log directory of the package. They are killed after the
+// given timeout, unless zero.
+func newRunOptions(h *host, name string, action string, timeout time.Duration) (*cmd.Options, error) {
+	dir := paths.LogDir(name)
+	if err := h.FS.MkdirAll(dir); err != nil {
+		return nil, fmt.Errorf("could not create log directory: %w", err)
 	}
 
-	installerCommand, err := installer.Command(path, installerType)
+	prefix := filepath.Join(dir, time.Now().Format("20060102-150405")+"-"+action)
+
+	return &cmd.Options{
+		LogFile:     prefix + ".log",
+		OutputFile:  prefix + "-output.log",
+		Timeout:     timeout,
+		IdleTimeout: idleWarningTimeout,
+	}, nil
+}
+
+// installerTimeout returns how long the installer of a package may run: its own timeout, if it has
+// one, or the given default.
+func installerTimeout(options *registry4.Options, defaultTimeout time.Duration) (time.Duration, error) {
+	if options == nil {
+		return defaultTimeout, nil
+	}
+
+	ret, err := options.TimeoutDuration()
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	if ret == 0 {
+		return defaultTimeout, nil
+	}
+
+	return ret, nil
+}
+
+// installArchive installs a package by extracting an archive of the given kind.
+func installArchive(h *host, path string, kind installer.ContainerKind, options *registry4.Options) (*installArtifacts, error) {
+	if options == nil {
+		return nil, fmt.Errorf("the %q installer requires additional options", kind)
+	}
+
+	if strings2.IsEmpty(options.Destination) {
+		return nil, errors.New("\"destination\" is missing from installer options")
+	}
+
+	destination, err := expandString(options.Destination, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not expand destination string: %w", err)
+	}
+
+	if err := h.FS.Extract(path, kind, destination, &installer.ExtractOptions{StripComponents: options.StripComponents}); err != nil {
+		return nil, err
+	}
+
+	return &installArtifacts{Destination: destination}, nil
+}
+
+// recordInstallation adds the given package to the installed-package database, keeping it on hold
+// if it already was.
+func recordInstallation(record *installdb.Record) error {
+	store, err := installdb.DefaultStore()
 	if err != nil {
 		return err
 	}
 
-	return cmd.Run(installerCommand...)
+	return store.Update(func(db *installdb.Database) error {
+		if previous, ok := db.Packages[record.Name]; ok {
+			record.Held = previous.Held
+		}
+
+		db.Packages[record.Name] = record
+		return nil
+	})
 }
 
 func exeproxyExists() bool {
@@ -338,37 +758,36 @@ func exeproxyExists() bool {
 	return dry.FileExists(exeproxy)
 }
 
-func createShims(options *registry4.Options) error {
+// createShims creates the shims listed in the given options, returning the paths of the ones that
+// were created.
+func createShims(h *host, options *registry4.Options) ([]string, error) {
 	exeproxy := os.ExpandEnv("${ProgramFiles(x86)}\\exeproxy\\exeproxy.exe")
 
-	if !dry.FileIsDir(shimsPath) {
-		if err := os.MkdirAll(shimsPath, 0); err != nil {
-			return fmt.Errorf("could not create shims directory %s: %w", shimsPath, err)
-		}
+	if err := h.FS.MkdirAll(shimsPath); err != nil {
+		return nil, fmt.Errorf("could not create shims directory %s: %w", shimsPath, err)
 	}
 
+	var ret []string
 	for _, v := range options.Shims {
 		shimTarget, err := expandString(v, map[string]string{})
 		if err != nil {
-			return err
+			return ret, err
 		}
 
 		shim := filepath.Join(shimsPath, filepath.Base(shimTarget))
 
-		if dry.FileExists(shim) {
-			if err := os.Remove(shim); err != nil {
-				return fmt.Errorf("could not re-create shim %s: %w", shim, err)
-			}
+		if err := h.FS.Remove(shim); err != nil {
+			return ret, fmt.Errorf("could not re-create shim %s: %w", shim, err)
 		}
 
-		log.Printf("creating shim for %s (%s)\n", shimTarget, shim)
-
-		if err := cmd.Run(exeproxy, "exeproxy-copy", shim, shimTarget); err != nil {
-			return fmt.Errorf("could not create shim %s for %s: %w", shim, shimTarget, err)
+		if err := h.Runner.Run(nil, exeproxy, "exeproxy-copy", shim, shimTarget); err != nil {
+			return ret, fmt.Errorf("could not create shim %s for %s: %w", shim, shimTarget, err)
 		}
+
+		ret = append(ret, shim)
 	}
 
-	return nil
+	return ret, nil
 }
 
 func isEmptyString(s string) bool {
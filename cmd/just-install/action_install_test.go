@@ -0,0 +1,74 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+func TestFetchInstallerChecksums(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	server := newInstallerServer(t)
+
+	tests := []struct {
+		name      string
+		url       string
+		checksum  string
+		languages []string
+		wantErr   bool
+	}{
+		{"matching checksum", "/setup.exe", sha256Hex("/setup.exe"), nil, false},
+		{"wrong checksum", "/setup.exe", sha256Hex("/other.exe"), nil, true},
+		{"localized installer", "/setup-{{.lang}}.exe", sha256Hex("/setup-en.exe"), []string{"de", "en"}, false},
+	}
+
+	for _, test := range tests {
+		entry := &registry4.Package{
+			Version: "1.0",
+			Installer: &registry4.Installer{
+				Kind:      "nsis",
+				X86:       server.URL + test.url,
+				Checksums: map[string]string{"x86": test.checksum},
+			},
+		}
+
+		settings := &installSettings{Arch: "x86", Languages: test.languages, IgnoreCache: true, Host: newHost(false)}
+		if settings.Languages == nil {
+			settings.Languages = []string{"en"}
+		}
+
+		_, _, err := fetchInstaller(entry, settings)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: got error %v, want error: %v", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestGetInstallArch(t *testing.T) {
+	// Packages of the v4 registry only have x86 and x86_64 installers, which ARM64 machines emulate
+	got, err := getInstallArch("")
+	if err != nil || (got != "x86" && got != "x86_64") {
+		t.Errorf("default architecture: got %q (%v), want x86 or x86_64", got, err)
+	}
+
+	for _, arch := range []string{"arm64", "mips"} {
+		if _, err := getInstallArch(arch); err == nil {
+			t.Errorf("%v: expected an error", arch)
+		}
+	}
+}
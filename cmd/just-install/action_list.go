@@ -17,11 +17,18 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/installdb"
 )
 
 func handleListAction(c *cli.Context) error {
+	if c.Bool("installed") {
+		return listInstalledPackages()
+	}
+
 	registry, err := loadRegistry(c, c.Bool("force"), !c.Bool("noprogress"))
 	if err != nil {
 		return err
@@ -30,7 +37,50 @@ func handleListAction(c *cli.Context) error {
 	packageNames := registry.SortedPackageNames()
 
 	for _, name := range packageNames {
-		fmt.Printf("%35v - %v\n", name, registry.Packages[name].Version)
+		pkg := registry.Packages[name]
+
+		var notes []string
+		if len(pkg.Aliases) > 0 {
+			notes = append(notes, "also known as "+strings.Join(pkg.Aliases, ", "))
+		}
+
+		if notice := deprecationNotice(pkg); notice != "" {
+			notes = append(notes, "deprecated: "+notice)
+		}
+
+		if len(notes) > 0 {
+			fmt.Printf("%35v - %v (%v)\n", name, pkg.Version, strings.Join(notes, "; "))
+		} else {
+			fmt.Printf("%35v - %v\n", name, pkg.Version)
+		}
+	}
+
+	return nil
+}
+
+// listInstalledPackages lists the packages recorded in the installed-package database.
+func listInstalledPackages() error {
+	store, err := installdb.DefaultStore()
+	if err != nil {
+		return err
+	}
+
+	db, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range db.SortedNames() {
+		record := db.Packages[name]
+
+		details := []string{record.Arch, record.Kind}
+		if record.Language != "" {
+			details = append(details, record.Language)
+		}
+
+		details = append(details, "installed "+record.InstalledAt.Local().Format("2006-01-02 15:04"))
+
+		fmt.Printf("%35v - %v (%v)\n", name, record.Version, strings.Join(details, ", "))
 	}
 
 	return nil
@@ -0,0 +1,123 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/installdb"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/versions"
+)
+
+// outdatedPackage is an installed package for which the registry has a newer version.
+type outdatedPackage struct {
+	Package   string `json:"package"`
+	Installed string `json:"installed"`
+	Available string `json:"available"`
+	Held      bool   `json:"held"`
+
+	record *installdb.Record
+	match  *registry4.Match
+}
+
+func handleOutdatedAction(c *cli.Context) error {
+	registry, err := loadRegistry(c, false, !c.Bool("noprogress"))
+	if err != nil {
+		return err
+	}
+
+	db, err := loadInstalledPackages()
+	if err != nil {
+		return err
+	}
+
+	outdated := outdatedPackages(registry, db)
+
+	switch c.String("format") {
+	case "", "text":
+		if len(outdated) == 0 {
+			fmt.Println("all installed packages are up to date")
+			return nil
+		}
+
+		fmt.Printf("%-35v %-20v %-20v\n", "PACKAGE", "INSTALLED", "AVAILABLE")
+		for _, p := range outdated {
+			if p.Held {
+				fmt.Printf("%-35v %-20v %-20v (held)\n", p.Package, p.Installed, p.Available)
+			} else {
+				fmt.Printf("%-35v %-20v %-20v\n", p.Package, p.Installed, p.Available)
+			}
+		}
+	case "json":
+		if outdated == nil {
+			outdated = []*outdatedPackage{}
+		}
+
+		out, err := marshalJSON(outdated)
+		if err != nil {
+			return err
+		}
+
+		return writeOutput("", out)
+	default:
+		return fmt.Errorf("unknown output format: %v", c.String("format"))
+	}
+
+	return nil
+}
+
+// loadInstalledPackages loads the installed-package database.
+func loadInstalledPackages() (*installdb.Database, error) {
+	store, err := installdb.DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Load()
+}
+
+// outdatedPackages returns the installed packages for which the registry has a newer version,
+// sorted by name. Packages that are no longer in the registry are ignored.
+func outdatedPackages(registry *registry4.Registry, db *installdb.Database) []*outdatedPackage {
+	var ret []*outdatedPackage
+
+	for _, name := range db.SortedNames() {
+		record := db.Packages[name]
+
+		match, ok := registry.Lookup(name)
+		if !ok {
+			continue
+		}
+
+		if versions.Compare(record.Version, match.Package.Version) >= 0 {
+			continue
+		}
+
+		ret = append(ret, &outdatedPackage{
+			Package:   name,
+			Installed: record.Version,
+			Available: match.Package.Version,
+			Held:      record.Held,
+			record:    record,
+			match:     match,
+		})
+	}
+
+	return ret
+}
@@ -0,0 +1,165 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/fetch"
+	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/paths"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// versionInFileNameRegexp matches something that looks like a version number in a file name.
+var versionInFileNameRegexp = regexp.MustCompile(`\d+(\.\d+)+`)
+
+func handleRegistryAddAction(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return errors.New("expected exactly one installer URL")
+	}
+
+	rawurl := c.Args().First()
+
+	parsedURL, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+
+	fileName := path.Base(parsedURL.Path)
+
+	name := c.String("name")
+	if name == "" {
+		name = strings.ToLower(strings.TrimSuffix(fileName, path.Ext(fileName)))
+		name = strings.Trim(versionInFileNameRegexp.ReplaceAllString(name, ""), "-_. ")
+	}
+
+	version := c.String("version")
+	if version == "" {
+		version = versionInFileNameRegexp.FindString(fileName)
+	}
+
+	if version == "" {
+		return errors.New("could not guess the version from the URL, please specify it with --version")
+	}
+
+	arch := c.String("arch")
+	if arch != "x86" && arch != "x86_64" {
+		return fmt.Errorf("unknown architecture: %v", arch)
+	}
+
+	// Download and inspect the installer
+	downloadDir, err := paths.TempDirCreate()
+	if err != nil {
+		return fmt.Errorf("could not create temporary directory to download installer: %w", err)
+	}
+
+	installerPath, err := fetch.Fetch(rawurl, &fetch.Options{Destination: downloadDir, Progress: !c.Bool("noprogress")})
+	if err != nil {
+		return err
+	}
+
+	detection, err := installer.Detect(installerPath)
+	if err != nil {
+		return fmt.Errorf("could not inspect %v: %w", installerPath, err)
+	}
+
+	log.Printf("detected %v (confidence: %v)", detection.Reason, detection.Confidence)
+	if detection.Confidence < installer.ConfidenceMedium {
+		log.Println("WARNING: please double check the installer kind of the new entry")
+	}
+
+	entry, err := scaffoldPackage(name, version, strings.Replace(rawurl, version, "{{.version}}", -1), arch, detection)
+	if err != nil {
+		return err
+	}
+
+	// Print or store the new entry
+	registryFile := c.String("file")
+	if registryFile == "" {
+		out, err := marshalJSON(map[string]*registry4.Package{name: entry})
+		if err != nil {
+			return err
+		}
+
+		return writeOutput("", out)
+	}
+
+	registry := registry4.New()
+	if _, err := os.Stat(registryFile); err == nil {
+		if registry, err = registry4.Load(registryFile); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := registry.Packages[name]; ok {
+		return fmt.Errorf("package %v already exists in %v", name, registryFile)
+	}
+
+	if err := registry.Set(name, entry); err != nil {
+		return err
+	}
+
+	log.Println("adding", name, "to", registryFile)
+
+	return registry.Save(registryFile)
+}
+
+// scaffoldPackage creates a new package entry for the given, already inspected, installer.
+func scaffoldPackage(name string, version string, url string, arch string, detection *installer.Detection) (*registry4.Package, error) {
+	ret := &registry4.Package{
+		Installer: &registry4.Installer{},
+		Version:   version,
+	}
+
+	if arch == "x86" {
+		ret.Installer.X86 = url
+	} else {
+		ret.Installer.X86_64 = url
+	}
+
+	var options *registry4.Options
+
+	switch {
+	case detection.Type == installer.SevenZipSFX:
+		ret.Installer.Kind = string(detection.Type)
+		options = &registry4.Options{Destination: "{{.PROGRAMFILES}}\\" + name}
+	case detection.Type != "":
+		ret.Installer.Kind = string(detection.Type)
+	case detection.Archive != "":
+		ret.Installer.Kind = detection.Archive
+		options = &registry4.Options{Destination: "{{.PROGRAMFILES}}\\" + name}
+	default:
+		return nil, errors.New("could not determine the installer kind")
+	}
+
+	if options != nil {
+		if err := ret.Installer.SetOptions(options); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,141 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/checkver"
+	"github.com/just-install/just-install/pkg/fetch"
+	"github.com/just-install/just-install/pkg/paths"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+func handleRegistryBumpAction(c *cli.Context) error {
+	registryFile := c.String("file")
+	if registryFile == "" {
+		return errors.New("please specify the registry file to update with --file")
+	}
+
+	if c.Args().Len() < 1 || c.Args().Len() > 2 {
+		return errors.New("expected a package name and, optionally, its new version")
+	}
+
+	registry, err := registry4.Load(registryFile)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args().Get(0)
+	entry, ok := registry.Packages[name]
+	if !ok {
+		return fmt.Errorf("unknown package: %v", name)
+	}
+
+	version := c.Args().Get(1)
+	if version == "" {
+		if entry.Checkver == nil {
+			return fmt.Errorf("no version given and %v has no \"checkver\" section", name)
+		}
+
+		if version, err = checkver.Latest(entry.Checkver); err != nil {
+			return fmt.Errorf("could not check the latest version of %v: %w", name, err)
+		}
+	}
+
+	if version == entry.Version {
+		log.Println(name, "is already at version", version)
+		return nil
+	}
+
+	updated := *entry
+	updated.Version = version
+
+	if len(entry.Installer.Checksums) > 0 {
+		installer := *entry.Installer
+		updated.Installer = &installer
+
+		if updated.Installer.Checksums, err = computeChecksums(&updated, !c.Bool("noprogress")); err != nil {
+			return err
+		}
+	}
+
+	if err := registry.Set(name, &updated); err != nil {
+		return err
+	}
+
+	log.Printf("updating %v from %v to %v in %v", name, entry.Version, version, registryFile)
+
+	return registry.Save(registryFile)
+}
+
+// computeChecksums downloads the installers of the given package whose checksums are tracked and
+// returns their new checksums. Installers whose URL depends on the language are left out, since
+// there is a different installer for each language and a single checksum per architecture.
+func computeChecksums(entry *registry4.Package, progress bool) (map[string]string, error) {
+	downloadDir, err := paths.TempDirCreate()
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary directory to download installers: %w", err)
+	}
+
+	var archs []string
+	for arch := range entry.Installer.Checksums {
+		archs = append(archs, arch)
+	}
+
+	sort.Strings(archs)
+
+	ret := make(map[string]string, len(archs))
+	for _, arch := range archs {
+		var installerURL string
+		switch arch {
+		case "x86":
+			installerURL = entry.Installer.X86
+		case "x86_64":
+			installerURL = entry.Installer.X86_64
+		}
+
+		if installerURL == "" {
+			return nil, fmt.Errorf("checksum given for %v but there is no installer for it", arch)
+		}
+
+		if langTemplateRegexp.MatchString(installerURL) {
+			log.Printf("WARNING: dropping the %v checksum, since the installer depends on the language", arch)
+			continue
+		}
+
+		expandedURL, err := expandString(installerURL, map[string]string{"version": entry.Version})
+		if err != nil {
+			return nil, fmt.Errorf("could not expand installer URL's template string: %w", err)
+		}
+
+		installerPath, err := fetch.Fetch(expandedURL, &fetch.Options{Destination: downloadDir, Overwrite: true, Progress: progress})
+		if err != nil {
+			return nil, err
+		}
+
+		if ret[arch], err = fetch.SHA256(installerPath); err != nil {
+			return nil, fmt.Errorf("could not compute checksum of %v: %w", installerPath, err)
+		}
+	}
+
+	return ret, nil
+}
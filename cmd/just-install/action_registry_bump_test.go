@@ -0,0 +1,66 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// newInstallerServer returns a test server serving fake installers whose contents are their path.
+func newInstallerServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestComputeChecksums(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	server := newInstallerServer(t)
+
+	entry := &registry4.Package{
+		Version: "1.2",
+		Installer: &registry4.Installer{
+			Kind:      "nsis",
+			X86:       server.URL + "/setup-{{.version}}.exe",
+			X86_64:    server.URL + "/setup-{{.version}}-{{.lang}}-x64.exe",
+			Checksums: map[string]string{"x86": "old", "x86_64": "old"},
+		},
+	}
+
+	got, err := computeChecksums(entry, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The checksum of the localized installer is dropped
+	if len(got) != 1 || got["x86"] != sha256Hex("/setup-1.2.exe") {
+		t.Errorf("got %v, want only the x86 checksum %v", got, sha256Hex("/setup-1.2.exe"))
+	}
+}
@@ -0,0 +1,166 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/checkver"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/registry5"
+	"github.com/just-install/just-install/pkg/versions"
+)
+
+// checkverWorkers is the number of packages checked concurrently.
+const checkverWorkers = 8
+
+// checkverResult is the outcome of checking the upstream version of a package.
+type checkverResult struct {
+	Package  string `json:"package"`
+	Current  string `json:"current"`
+	Latest   string `json:"latest,omitempty"`
+	Outdated bool   `json:"outdated"`
+	Error    string `json:"error,omitempty"`
+}
+
+func handleRegistryCheckverAction(c *cli.Context) error {
+	var registry *registry4.Registry
+	var err error
+
+	if registryFile := c.String("file"); registryFile != "" {
+		registry, err = registry5.LoadCompatible(registryFile)
+	} else {
+		registry, err = loadRegistry(c, false, !c.Bool("noprogress"))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	names := c.Args().Slice()
+	if len(names) == 0 {
+		for name, entry := range registry.Packages {
+			if entry.Checkver != nil {
+				names = append(names, name)
+			}
+		}
+	} else {
+		for _, name := range names {
+			entry, ok := registry.Packages[name]
+			if !ok {
+				return fmt.Errorf("unknown package: %v", name)
+			} else if entry.Checkver == nil {
+				return fmt.Errorf("package %v has no \"checkver\" section", name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	results := checkPackageVersions(registry, names)
+
+	failed := 0
+	outdated := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		} else if result.Outdated {
+			outdated++
+		}
+	}
+
+	switch c.String("format") {
+	case "", "text":
+		for _, result := range results {
+			switch {
+			case result.Error != "":
+				fmt.Printf("%v: %v\n", result.Package, result.Error)
+			case result.Outdated:
+				fmt.Printf("%v: %v -> %v\n", result.Package, result.Current, result.Latest)
+			case c.Bool("all"):
+				fmt.Printf("%v: %v (up to date)\n", result.Package, result.Current)
+			}
+		}
+
+		fmt.Printf("%v packages checked, %v outdated, %v failed\n", len(results), outdated, failed)
+	case "json":
+		out, err := marshalJSON(results)
+		if err != nil {
+			return err
+		}
+
+		if err := writeOutput("", out); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown output format: %v", c.String("format"))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("could not check %v packages", failed)
+	}
+
+	return nil
+}
+
+// checkPackageVersions checks the upstream version of the given packages concurrently, returning
+// the results in the same order.
+func checkPackageVersions(registry *registry4.Registry, names []string) []*checkverResult {
+	results := make([]*checkverResult, len(names))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < checkverWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for index := range indexes {
+				results[index] = checkPackageVersion(names[index], registry.Packages[names[index]])
+			}
+		}()
+	}
+
+	for i := range names {
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// checkPackageVersion checks the upstream version of a single package.
+func checkPackageVersion(name string, entry *registry4.Package) *checkverResult {
+	ret := &checkverResult{Package: name, Current: entry.Version}
+
+	latest, err := checkver.Latest(entry.Checkver)
+	if err != nil {
+		ret.Error = err.Error()
+		return ret
+	}
+
+	ret.Latest = latest
+	ret.Outdated = versions.Compare(entry.Version, latest) < 0
+
+	return ret
+}
@@ -0,0 +1,125 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/dataformat"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/registry5"
+)
+
+func handleRegistryConvertAction(c *cli.Context) error {
+	if c.Args().Len() < 1 || c.Args().Len() > 2 {
+		return errors.New("expected a registry file to convert and, optionally, an output file")
+	}
+
+	src := c.Args().Get(0)
+	dst := c.Args().Get(1)
+
+	if dst == "" && c.String("to") == "" {
+		return errors.New("\"--to\" is required when writing to standard output")
+	}
+
+	format, err := outputFormat(dst, c.String("to"))
+	if err != nil {
+		return err
+	}
+
+	b, err := readDocument(src)
+	if err != nil {
+		return fmt.Errorf("could not read %v: %w", src, err)
+	}
+
+	registry, err := loadRegistryDocument(src, b)
+	if err != nil {
+		return fmt.Errorf("could not convert %v: %w", src, err)
+	}
+
+	return saveRegistryDocument(registry, dst, format)
+}
+
+// registryDocument is a registry of any supported version, in the model of its version.
+type registryDocument interface {
+	Marshal(format dataformat.Format) ([]byte, error)
+	SaveAs(path string, format dataformat.Format) error
+}
+
+// loadRegistryDocument loads the registry file at path, whose contents converted to JSON are given,
+// in the model of its version. Going through the model, rather than converting the document
+// directly, validates it exactly like a registry loaded by the installer would be. Since the model
+// would silently drop them, fields it doesn't know about are an error.
+func loadRegistryDocument(path string, jsonBytes []byte) (registryDocument, error) {
+	version, err := registry5.DetectVersion(jsonBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case registry4.FormatVersion:
+		if err := checkKnownFields(jsonBytes, &registry4.Registry{}); err != nil {
+			return nil, err
+		}
+
+		return registry4.Load(path)
+	case registry5.FormatVersion:
+		if err := checkKnownFields(jsonBytes, &registry5.Registry{}); err != nil {
+			return nil, err
+		}
+
+		return registry5.Load(path)
+	default:
+		return nil, fmt.Errorf("unsupported registry version: %v", version)
+	}
+}
+
+// checkKnownFields decodes the given JSON document into v, failing if the document has fields that
+// v doesn't have.
+func checkKnownFields(jsonBytes []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w, which would be lost", err)
+	}
+
+	return nil
+}
+
+// saveRegistryDocument writes the given registry to the file at path, replacing it atomically, or to
+// standard output if the path is empty or "-".
+func saveRegistryDocument(registry registryDocument, path string, format dataformat.Format) error {
+	if path == "" || path == "-" {
+		out, err := registry.Marshal(format)
+		if err != nil {
+			return err
+		}
+
+		return writeOutput("", out)
+	}
+
+	if err := registry.SaveAs(path, format); err != nil {
+		return fmt.Errorf("could not write %v: %w", path, err)
+	}
+
+	return nil
+}
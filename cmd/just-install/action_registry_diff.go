@@ -0,0 +1,110 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/registry5"
+)
+
+func handleRegistryDiffAction(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return errors.New("expected exactly two registry files to compare")
+	}
+
+	oldRegistry, err := registry5.LoadCompatible(c.Args().Get(0))
+	if err != nil {
+		return fmt.Errorf("could not load %v: %w", c.Args().Get(0), err)
+	}
+
+	newRegistry, err := registry5.LoadCompatible(c.Args().Get(1))
+	if err != nil {
+		return fmt.Errorf("could not load %v: %w", c.Args().Get(1), err)
+	}
+
+	return printChanges(registry4.Diff(oldRegistry, newRegistry), c.String("format"))
+}
+
+// printChanges prints the given registry changes to standard output, either as human readable text
+// or as JSON.
+func printChanges(changes *registry4.Changes, format string) error {
+	switch format {
+	case "", "text":
+	case "json":
+		out, err := marshalJSON(changes)
+		if err != nil {
+			return err
+		}
+
+		return writeOutput("", out)
+	default:
+		return fmt.Errorf("unknown output format: %v", format)
+	}
+
+	if changes.IsEmpty() {
+		fmt.Println("no changes")
+		return nil
+	}
+
+	for _, change := range changes.Added {
+		fmt.Printf("+ %v %v\n", change.Name, change.NewVersion)
+	}
+
+	for _, change := range changes.Removed {
+		fmt.Printf("- %v %v\n", change.Name, change.OldVersion)
+	}
+
+	for _, change := range changes.Changed {
+		var what []string
+		if change.VersionChanged {
+			what = append(what, "version")
+		}
+		if change.KindChanged {
+			what = append(what, "kind")
+		}
+		if change.URLChanged {
+			what = append(what, "url")
+		}
+		if change.OptionsChanged {
+			what = append(what, "options")
+		}
+		if change.ChecksumsChanged {
+			what = append(what, "checksums")
+		}
+		if change.AliasesChanged {
+			what = append(what, "aliases")
+		}
+		if change.DeprecatedChanged {
+			what = append(what, "deprecation")
+		}
+
+		if change.VersionChanged {
+			fmt.Printf("~ %v %v -> %v (%v)\n", change.Name, change.OldVersion, change.NewVersion, strings.Join(what, ", "))
+		} else {
+			fmt.Printf("~ %v %v (%v)\n", change.Name, change.NewVersion, strings.Join(what, ", "))
+		}
+	}
+
+	fmt.Printf("\n%v added, %v removed, %v changed\n", len(changes.Added), len(changes.Removed), len(changes.Changed))
+
+	return nil
+}
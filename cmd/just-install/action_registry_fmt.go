@@ -0,0 +1,94 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/dataformat"
+)
+
+func handleRegistryFmtAction(c *cli.Context) error {
+	if c.Args().Len() < 1 {
+		return errors.New("expected at least one registry file to format")
+	}
+
+	check := c.Bool("check")
+	var unformatted []string
+
+	for _, path := range c.Args().Slice() {
+		original, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		format := dataformat.Detect(path, original)
+
+		registry, formatted, err := formatRegistry(path, original, format)
+		if err != nil {
+			return fmt.Errorf("could not format %v: %w", path, err)
+		}
+
+		if bytes.Equal(original, formatted) {
+			continue
+		}
+
+		unformatted = append(unformatted, path)
+
+		if check {
+			fmt.Println(path)
+			continue
+		}
+
+		log.Println("formatting", path)
+		if err := saveRegistryDocument(registry, path, format); err != nil {
+			return err
+		}
+	}
+
+	if check && len(unformatted) > 0 {
+		return fmt.Errorf("%v file(s) are not formatted canonically", len(unformatted))
+	}
+
+	return nil
+}
+
+// formatRegistry loads the given registry file, in the given format, and returns it along with its
+// canonical representation in that format.
+func formatRegistry(path string, b []byte, format dataformat.Format) (registryDocument, []byte, error) {
+	jsonBytes, err := dataformat.ToJSON(b, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	registry, err := loadRegistryDocument(path, jsonBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	formatted, err := registry.Marshal(format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return registry, formatted, nil
+}
@@ -0,0 +1,91 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/just-install/just-install/pkg/dataformat"
+)
+
+func TestFormatRegistryUnknownFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string // Empty if the registry must be formatted
+	}{
+		{
+			"v4",
+			`{"version": 4, "packages": {"tool": {"version": "1", "installer": {"kind": "as-is", "x86": "https://example.com/tool.exe"}}}}`,
+			"",
+		},
+		{
+			"v4, unknown option is kept",
+			`{"version": 4, "packages": {"tool": {"version": "1", "installer": {"kind": "as-is", "x86": "https://example.com/tool.exe", "options": {"future": true}}}}}`,
+			"",
+		},
+		{
+			"v4, unknown top-level field",
+			`{"version": 4, "mirror": "https://example.com", "packages": {}}`,
+			`unknown field "mirror"`,
+		},
+		{
+			"v4, unknown package field",
+			`{"version": 4, "packages": {"tool": {"version": "1", "homepage": "https://example.com", "installer": {"kind": "as-is", "x86": "https://example.com/tool.exe"}}}}`,
+			`unknown field "homepage"`,
+		},
+		{
+			"v5",
+			`{"version": 5, "packages": {"tool": {"version": "1", "installers": {"x86": {"kind": "as-is", "url": "https://example.com/tool.exe", "options": {"shims": ["tool.exe"]}}}}}}`,
+			"",
+		},
+		{
+			"v5, unknown installer field",
+			`{"version": 5, "packages": {"tool": {"version": "1", "installers": {"x86": {"kind": "as-is", "url": "https://example.com/tool.exe", "mirror": "https://example.org/tool.exe"}}}}}`,
+			`unknown field "mirror"`,
+		},
+		{
+			"v5, unknown option",
+			`{"version": 5, "packages": {"tool": {"version": "1", "installers": {"x86": {"kind": "as-is", "url": "https://example.com/tool.exe", "options": {"future": true}}}}}}`,
+			`unknown field "future"`,
+		},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "registry.json")
+		if err := ioutil.WriteFile(path, []byte(test.doc), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, formatted, err := formatRegistry(path, []byte(test.doc), dataformat.JSON)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%v: got error %v, want %q", test.name, err, test.wantErr)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		} else if strings.Contains(test.doc, "future") && !strings.Contains(string(formatted), `"future": true`) {
+			t.Errorf("%v: unknown option was dropped:\n%s", test.name, formatted)
+		}
+	}
+}
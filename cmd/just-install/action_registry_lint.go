@@ -0,0 +1,86 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/lint"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/registry5"
+)
+
+func handleRegistryLintAction(c *cli.Context) error {
+	if c.Bool("list-rules") {
+		for _, rule := range lint.Rules() {
+			fmt.Printf("%40v - %v\n", rule.Name, rule.Description)
+		}
+
+		return nil
+	}
+
+	disabled := c.StringSlice("disable")
+	for _, name := range disabled {
+		if !lint.IsValidRule(name) {
+			return fmt.Errorf("unknown lint rule: %v", name)
+		}
+	}
+
+	var registry *registry4.Registry
+	var err error
+
+	switch c.Args().Len() {
+	case 0:
+		registry, err = loadRegistry(c, false, !c.Bool("noprogress"))
+	case 1:
+		registry, err = registry5.LoadCompatible(c.Args().First())
+	default:
+		return errors.New("expected at most one registry file to lint")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	findings := lint.Run(registry, &lint.Options{Disabled: disabled})
+
+	switch c.String("format") {
+	case "", "text":
+		for _, finding := range findings {
+			fmt.Printf("%v: [%v] %v\n", finding.Package, finding.Rule, finding.Message)
+		}
+	case "json":
+		out, err := marshalJSON(findings)
+		if err != nil {
+			return err
+		}
+
+		if err := writeOutput("", out); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown output format: %v", c.String("format"))
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("found %v problems", len(findings))
+	}
+
+	return nil
+}
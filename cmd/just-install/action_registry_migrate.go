@@ -0,0 +1,72 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/registry5"
+)
+
+func handleRegistryMigrateAction(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return errors.New("expected exactly one registry file to migrate")
+	}
+
+	src := c.Args().First()
+
+	b, err := readDocument(src)
+	if err != nil {
+		return err
+	}
+
+	version, err := registry5.DetectVersion(b)
+	if err != nil {
+		return fmt.Errorf("could not read %v: %w", src, err)
+	}
+
+	if version != registry4.FormatVersion {
+		return fmt.Errorf("%v is a v%v registry, only v4 registries can be migrated", src, version)
+	}
+
+	r4, err := registry4.Load(src)
+	if err != nil {
+		return fmt.Errorf("could not load %v: %w", src, err)
+	}
+
+	r5, err := registry5.FromV4(r4)
+	if err != nil {
+		return fmt.Errorf("could not migrate %v: %w", src, err)
+	}
+
+	r5.Schema = c.String("schema")
+
+	// Paranoia: make sure we didn't lose anything along the way.
+	if err := registry5.Verify(r4, r5); err != nil {
+		return fmt.Errorf("migration of %v is not lossless: %w", src, err)
+	}
+
+	format, err := outputFormat(c.String("output"), c.String("to"))
+	if err != nil {
+		return err
+	}
+
+	return saveRegistryDocument(r5, c.String("output"), format)
+}
@@ -0,0 +1,226 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/installdb"
+	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+func handleUninstallAction(c *cli.Context) error {
+	if c.Args().Len() < 1 {
+		return errors.New("expected at least one package name")
+	}
+
+	store, err := installdb.DefaultStore()
+	if err != nil {
+		return err
+	}
+
+	db, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	// The registry is only needed for packages installed before their uninstaller was declared
+	var registry *registry4.Registry
+	currentOptions := func(record *installdb.Record) *registry4.Options {
+		if registry == nil {
+			loaded, err := loadRegistry(c, false, !c.Bool("noprogress"))
+			if err != nil {
+				log.Printf("WARNING: could not load the registry: %v", err)
+				loaded = registry4.New()
+			}
+
+			registry = loaded
+		}
+
+		match, ok := registry.Lookup(record.Name)
+		if !ok {
+			return nil
+		}
+
+		options, err := match.Package.Installer.OptionsForArch(record.Arch)
+		if err != nil {
+			return nil
+		}
+
+		return options
+	}
+
+	h := hostFromContext(c)
+	hasErrors := false
+
+	for _, name := range c.Args().Slice() {
+		record, ok := db.Lookup(name)
+		if !ok {
+			log.Printf("WARNING: %v is not installed", name)
+			hasErrors = true
+			continue
+		}
+
+		if err := uninstallAndForget(h, store, record, currentOptions, c.Duration("timeout")); err != nil {
+			log.Printf("error uninstalling %v: %v", record.Name, err)
+			hasErrors = true
+		}
+	}
+
+	if hasErrors {
+		return errors.New("encountered errors uninstalling packages (see the log for details)")
+	}
+
+	return nil
+}
+
+// uninstallAndForget uninstalls a package and then removes its record from the store. The record is
+// kept if the package could not be uninstalled, so that the uninstallation can be retried.
+func uninstallAndForget(h *host, store *installdb.Store, record *installdb.Record, currentOptions func(*installdb.Record) *registry4.Options, timeout time.Duration) error {
+	if err := uninstall(h, record, currentOptions, timeout); err != nil {
+		return err
+	}
+
+	if h.DryRun {
+		log.Printf("would forget that %v is installed", record.Name)
+		return nil
+	}
+
+	if err := store.Remove(record.Name); err != nil {
+		log.Printf("WARNING: %v was uninstalled but its record could not be removed: %v", record.Name, err)
+	}
+
+	log.Println("uninstalled", record.Name)
+
+	return nil
+}
+
+// uninstall removes an installed package. The current registry options of the package are used to
+// find its uninstaller when it wasn't recorded at installation time. The uninstaller is killed after
+// the given timeout, unless zero.
+func uninstall(h *host, record *installdb.Record, currentOptions func(*installdb.Record) *registry4.Options, timeout time.Duration) error {
+	switch {
+	case record.Kind == "copy":
+		if err := h.FS.Remove(record.Destination); err != nil {
+			return err
+		}
+
+		// Also remove the directory created to hold the file, unless something else is in there
+		if !h.DryRun {
+			os.Remove(filepath.Dir(record.Destination))
+		}
+	case isArchiveKind(record.Kind):
+		if err := checkRemovableDirectory(record.Destination); err != nil {
+			return err
+		}
+
+		if err := h.FS.RemoveAll(record.Destination); err != nil {
+			return err
+		}
+	default:
+		productCode := record.ProductCode
+		arguments := record.Uninstall
+
+		if productCode == "" && len(arguments) == 0 {
+			if options := currentOptions(record); options != nil && options.Uninstall != nil {
+				productCode = options.Uninstall.ProductCode
+				arguments = options.Uninstall.Arguments
+			}
+		}
+
+		runOptions, err := newRunOptions(h, record.Name, "uninstall", timeout)
+		if err != nil {
+			return err
+		}
+
+		var command []string
+		var commandType installer.InstallerType // Empty for custom uninstallers
+		switch {
+		case len(arguments) > 0:
+			for _, v := range arguments {
+				expanded, err := expandString(v, map[string]string{"destination": record.Destination})
+				if err != nil {
+					return err
+				}
+
+				command = append(command, expanded)
+			}
+		case record.Kind == string(installer.MSI) && productCode != "":
+			command = installer.MSIUninstallCommand(productCode, runOptions.LogFile)
+			commandType = installer.MSI
+		case record.Kind == string(installer.MSI):
+			return errors.New("the product code of this package is unknown, please add \"uninstall\": {\"productCode\": ...} to its registry entry")
+		default:
+			return errors.New("this package has no uninstaller, please add \"uninstall\": {\"arguments\": [...]} to its registry entry")
+		}
+
+		status, err := runInstaller(h, commandType, runOptions, command...)
+		if err != nil {
+			return err
+		}
+
+		if status == installer.ExitSuccessRebootRequired {
+			log.Println("a reboot is required to complete the removal")
+		}
+	}
+
+	for _, path := range record.Shortcuts {
+		if err := removeShortcut(h, path, installer.Scope(record.Scope)); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range record.Shims {
+		if err := h.FS.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	if err := revertEnvironment(h, record); err != nil {
+		log.Printf("WARNING: could not undo the environment changes of %v: %v", record.Name, err)
+	}
+
+	return nil
+}
+
+// checkRemovableDirectory refuses to recursively remove directories that can't possibly be the
+// installation directory of a single package, as a safety net against corrupted records.
+func checkRemovableDirectory(path string) error {
+	if path == "" {
+		return errors.New("installation directory is unknown")
+	}
+
+	path = filepath.Clean(path)
+	if !filepath.IsAbs(path) || filepath.Dir(path) == path {
+		return fmt.Errorf("refusing to remove %v", path)
+	}
+
+	for _, env := range []string{"ProgramData", "ProgramFiles", "ProgramFiles(x86)", "SystemDrive", "SystemRoot", "USERPROFILE", "HOME"} {
+		if value := os.Getenv(env); value != "" && filepath.Clean(value) == path {
+			return fmt.Errorf("refusing to remove %v", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/just-install/just-install/pkg/cmd"
+	"github.com/just-install/just-install/pkg/installdb"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+func TestCheckRemovableDirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	root := home
+	for filepath.Dir(root) != root {
+		root = filepath.Dir(root)
+	}
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"", false},
+		{"tool", false},
+		{filepath.Join("..", "tool"), false},
+		{root, false},
+		{home, false},
+		{home + string(filepath.Separator), false},
+		{filepath.Join(home, "tool", ".."), false},
+		{filepath.Join(home, "tool"), true},
+		{filepath.Join(home, "..", "tool"), true},
+	}
+
+	for _, test := range tests {
+		if err := checkRemovableDirectory(test.path); (err == nil) != test.ok {
+			t.Errorf("%q: got error %v, want removable %v", test.path, err, test.ok)
+		}
+	}
+}
+
+// noCurrentOptions is the currentOptions function of uninstall for packages not in the registry.
+func noCurrentOptions(*installdb.Record) *registry4.Options {
+	return nil
+}
+
+func TestUninstallAndForget(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("JUST_INSTALL_DATA_DIR", t.TempDir())
+
+	tests := []struct {
+		name       string
+		record     *installdb.Record
+		dryRun     bool
+		codes      []int // Exit codes of the uninstaller
+		wantErr    bool
+		wantRecord bool
+	}{
+		{"archive", &installdb.Record{Kind: "zip"}, false, nil, false, false},
+		{"unsafe archive destination", &installdb.Record{Kind: "zip", Destination: home}, false, nil, true, true},
+		{"dry run", &installdb.Record{Kind: "zip"}, true, nil, false, true},
+		{"uninstaller succeeds", &installdb.Record{Kind: "msi", ProductCode: "{00000000-0000-0000-0000-000000000000}"}, false, []int{0}, false, false},
+		{"uninstaller fails", &installdb.Record{Kind: "msi", ProductCode: "{00000000-0000-0000-0000-000000000000}"}, false, []int{1603}, true, true},
+		{"no uninstaller", &installdb.Record{Kind: "msi"}, false, nil, true, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.record.Name = "tool"
+			if test.record.Kind == "zip" && test.record.Destination == "" {
+				test.record.Destination = filepath.Join(t.TempDir(), "tool")
+
+				if err := os.MkdirAll(test.record.Destination, 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := ioutil.WriteFile(filepath.Join(test.record.Destination, "tool.exe"), []byte("MZ"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			store := &installdb.Store{Path: filepath.Join(t.TempDir(), installdb.FileName)}
+			if err := store.Update(func(db *installdb.Database) error {
+				db.Packages[test.record.Name] = test.record
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			h := newHost(test.dryRun)
+			h.Runner = &exitCodeRunner{codes: test.codes}
+			if test.dryRun {
+				h.Runner = cmd.DryRunner{}
+			}
+
+			err := uninstallAndForget(h, store, test.record, noCurrentOptions, 0)
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error %v", err, test.wantErr)
+			}
+
+			db, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := db.Lookup(test.record.Name); ok != test.wantRecord {
+				t.Errorf("record kept %v, want %v", ok, test.wantRecord)
+			}
+
+			if _, err := os.Stat(home); err != nil {
+				t.Errorf("home directory was removed: %v", err)
+			}
+
+			if test.record.Kind == "zip" && test.record.Destination != home {
+				_, err := os.Stat(test.record.Destination)
+				if removed := os.IsNotExist(err); removed != !test.wantRecord {
+					t.Errorf("destination removed %v, want %v", removed, !test.wantRecord)
+				}
+			}
+		})
+	}
+}
@@ -16,11 +16,56 @@
 package main
 
 import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ungerik/go-dry"
 	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/registry5"
 )
 
 func handleUpdateAction(c *cli.Context) error {
-	_, err := loadRegistry(c, true, !c.Bool("noprogress"))
+	_, dst, err := registryPaths(c)
+	if err != nil {
+		return err
+	}
+
+	// Keep the previous registry around, if any, to tell the user what changed.
+	var previous *registry4.Registry
+	if dry.FileExists(dst) {
+		previous, err = registry5.LoadCompatible(dst)
+		if err != nil {
+			log.Println("WARNING: could not load the previous registry:", err)
+		}
+	}
+
+	var current *registry4.Registry
+	if c.Bool("rollback") {
+		generation, err := registryStore(dst).Rollback()
+		if err != nil {
+			return fmt.Errorf("could not roll back the registry: %w", err)
+		}
+
+		log.Println("rolled back to the registry downloaded on", generation.Time.Local().Format(time.RFC1123))
+
+		current, err = registry5.LoadCompatible(dst)
+		if err != nil {
+			return err
+		}
+	} else {
+		current, err = updateRegistry(c, !c.Bool("noprogress"))
+		if err != nil {
+			return fmt.Errorf("could not update the registry: %w", err)
+		}
+	}
+
+	if previous == nil {
+		log.Println("no previous registry to compare with")
+		return nil
+	}
 
-	return err
+	return printChanges(registry4.Diff(previous, current), c.String("format"))
 }
@@ -0,0 +1,233 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/ungerik/go-dry"
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/installdb"
+	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/language"
+)
+
+func handleUpgradeAction(c *cli.Context) error {
+	all := c.Bool("all")
+	if all == (c.Args().Len() > 0) {
+		return errors.New("please specify either the packages to upgrade or --all")
+	}
+
+	progress := !c.Bool("noprogress")
+
+	registry, err := loadRegistry(c, false, progress)
+	if err != nil {
+		return err
+	}
+
+	db, err := loadInstalledPackages()
+	if err != nil {
+		return err
+	}
+
+	outdated := map[string]*outdatedPackage{}
+	for _, p := range outdatedPackages(registry, db) {
+		outdated[p.Package] = p
+	}
+
+	names := c.Args().Slice()
+	if all {
+		names = db.SortedNames()
+	}
+
+	defaults := &installSettings{
+		Languages: language.Preferences(c.String("lang")),
+		Progress:  progress,
+		Timeout:   c.Duration("timeout"),
+		Host:      hostFromContext(c),
+	}
+
+	var upgraded, upToDate, held, rebootRequired, failed []string
+
+	for _, name := range names {
+		record, ok := db.Lookup(name)
+		if !ok {
+			log.Printf("WARNING: %v is not installed", name)
+			failed = append(failed, name)
+			continue
+		}
+
+		p, ok := outdated[record.Name]
+		if !ok {
+			upToDate = append(upToDate, record.Name)
+			continue
+		}
+
+		if record.Held {
+			log.Printf("skipping %v, which is held at version %v", record.Name, record.Version)
+			held = append(held, record.Name)
+			continue
+		}
+
+		log.Printf("upgrading %v from %v to %v", record.Name, p.Installed, p.Available)
+		status, err := upgradePackage(p, defaults)
+		if err != nil {
+			log.Printf("error upgrading %v: %v", record.Name, err)
+			failed = append(failed, record.Name)
+			continue
+		}
+
+		upgraded = append(upgraded, record.Name)
+		if status == installer.ExitSuccessRebootRequired {
+			rebootRequired = append(rebootRequired, record.Name)
+		}
+	}
+
+	fmt.Println()
+	if defaults.Host.DryRun {
+		printSummary("would be upgraded", upgraded)
+	} else {
+		printSummary("upgraded", upgraded)
+	}
+
+	printSummary("up to date", upToDate)
+	printSummary("held", held)
+	printSummary("failed", failed)
+
+	if len(rebootRequired) > 0 {
+		log.Printf("a reboot is required to complete the upgrade of: %v", strings.Join(rebootRequired, ", "))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not upgrade %v packages (see the log for details)", len(failed))
+	}
+
+	return nil
+}
+
+// upgradePackage installs the newer version of an outdated package, using the same architecture,
+// language and scope as the installed one. Other settings are taken from defaults.
+func upgradePackage(p *outdatedPackage, defaults *installSettings) (installer.ExitStatus, error) {
+	settings := *defaults
+	settings.Arch = p.record.Arch
+	settings.Scope = installer.Scope(p.record.Scope)
+
+	if p.record.Language != "" {
+		settings.Languages = append([]string{p.record.Language}, defaults.Languages...)
+	}
+
+	status, err := installPackage(p.match.Name, p.match.Package, &settings, p.record)
+	if err != nil {
+		return status, err
+	}
+
+	// The package was installed under its new name
+	if p.match.Name != p.record.Name {
+		if settings.Host.DryRun {
+			log.Printf("would forget that %v is installed", p.record.Name)
+			return status, nil
+		}
+
+		store, err := installdb.DefaultStore()
+		if err != nil {
+			return status, err
+		}
+
+		return status, store.Remove(p.record.Name)
+	}
+
+	return status, nil
+}
+
+// prepareUpgrade makes room for a new version of an installed package, when its installer kind
+// can't upgrade it in place. The previous version is moved aside rather than removed, and the
+// returned backup, if not nil, must be either discarded once the new version is installed or
+// restored if installing it fails.
+func prepareUpgrade(h *host, previous *installdb.Record, kind string) (*upgradeBackup, error) {
+	switch {
+	case isArchiveKind(previous.Kind) && isArchiveKind(kind) && previous.Destination != "":
+		// Extracting over the old version would leave behind files that were removed upstream
+		if err := checkRemovableDirectory(previous.Destination); err != nil {
+			return nil, fmt.Errorf("could not replace previous version: %w", err)
+		}
+
+		if !h.DryRun && !dry.FileExists(previous.Destination) {
+			return nil, nil
+		}
+
+		ret := &upgradeBackup{h, previous.Destination, filepath.Clean(previous.Destination) + ".just-install-backup"}
+
+		// Left behind by an upgrade that was interrupted
+		if dry.FileExists(ret.backup) {
+			if err := h.FS.RemoveAll(ret.backup); err != nil {
+				return nil, fmt.Errorf("could not remove old backup: %w", err)
+			}
+		}
+
+		if err := h.FS.Rename(ret.destination, ret.backup); err != nil {
+			return nil, fmt.Errorf("could not move previous version aside: %w", err)
+		}
+
+		return ret, nil
+	}
+
+	return nil, nil
+}
+
+// upgradeBackup is the previous version of a package, moved aside while the new one is installed.
+type upgradeBackup struct {
+	h           *host
+	destination string
+	backup      string
+}
+
+// discard removes the previous version, once the new one is installed.
+func (b *upgradeBackup) discard() {
+	if err := b.h.FS.RemoveAll(b.backup); err != nil {
+		log.Printf("WARNING: could not remove the previous version from %v: %v", b.backup, err)
+	}
+}
+
+// restore puts the previous version back in place, after installing the new one failed.
+func (b *upgradeBackup) restore() {
+	if err := b.h.FS.RemoveAll(b.destination); err != nil {
+		log.Printf("WARNING: could not remove what was installed of the new version: %v", err)
+	}
+
+	if err := b.h.FS.Rename(b.backup, b.destination); err != nil {
+		log.Printf("WARNING: could not restore the previous version, it's still in %v: %v", b.backup, err)
+	}
+}
+
+// isArchiveKind returns whether packages of the given kind are installed by extracting an archive,
+// either directly or by running a self-extracting archive.
+func isArchiveKind(kind string) bool {
+	return installer.ContainerKind(kind).IsValid() || kind == string(installer.SevenZipSFX)
+}
+
+// printSummary prints a line of the summary printed at the end of an installation or upgrade.
+func printSummary(what string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Printf("%v %v: %v\n", len(names), what, strings.Join(names, ", "))
+}
@@ -0,0 +1,80 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/just-install/just-install/pkg/installdb"
+)
+
+func TestPrepareUpgrade(t *testing.T) {
+	destination := filepath.Join(t.TempDir(), "app")
+	oldFile := filepath.Join(destination, "old.txt")
+
+	for _, restore := range []bool{false, true} {
+		if err := os.MkdirAll(destination, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(oldFile, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		backup, err := prepareUpgrade(newHost(false), &installdb.Record{Kind: "zip", Destination: destination}, "zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if backup == nil {
+			t.Fatal("previous version was not moved aside")
+		}
+
+		if _, err := os.Stat(destination); !os.IsNotExist(err) {
+			t.Errorf("previous version is still in place: %v", err)
+		}
+
+		if restore {
+			backup.restore()
+
+			if _, err := os.Stat(oldFile); err != nil {
+				t.Errorf("previous version was not restored: %v", err)
+			}
+		} else {
+			backup.discard()
+
+			if _, err := os.Stat(backup.backup); !os.IsNotExist(err) {
+				t.Errorf("backup was not removed: %v", err)
+			}
+		}
+	}
+}
+
+func TestPrepareUpgradeRefusesUnsafeDestinations(t *testing.T) {
+	for _, destination := range []string{"", "relative", string(filepath.Separator)} {
+		record := &installdb.Record{Kind: "zip", Destination: destination}
+		if backup, err := prepareUpgrade(newHost(false), record, "zip"); err == nil && backup != nil {
+			t.Errorf("%q was moved aside", destination)
+		}
+	}
+
+	if _, err := prepareUpgrade(newHost(false), &installdb.Record{Kind: "zip", Destination: string(filepath.Separator)}, "zip"); err == nil {
+		t.Error("the root directory can be replaced")
+	}
+}
@@ -0,0 +1,141 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/just-install/just-install/pkg/environment"
+	"github.com/just-install/just-install/pkg/installdb"
+	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// applyEnvironment adds the PATH entries and sets the environment variables listed in the given
+// options, in the environment of the current user for packages installed with the user scope and
+// in the one of the machine otherwise. It returns the changes that were made, so that they can be
+// reverted, even if it fails halfway.
+func applyEnvironment(h *host, options *registry4.Options, scope installer.Scope) ([]string, []*installdb.EnvironmentChange, error) {
+	if options == nil || (len(options.Path) == 0 && len(options.Environment) == 0) {
+		return nil, nil, nil
+	}
+
+	env, err := h.Environment(scope == installer.ScopeUser)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var path []string
+	for _, v := range options.Path {
+		dir, err := expandString(v, nil)
+		if err != nil {
+			return path, nil, fmt.Errorf("could not expand PATH entry: %w", err)
+		}
+
+		added, err := environment.AddToPath(env, dir)
+		if err != nil {
+			return path, nil, fmt.Errorf("could not add %v to PATH: %w", dir, err)
+		}
+
+		if !added {
+			log.Println(dir, "is already in PATH")
+			continue
+		}
+
+		h.logChange("added %v to PATH", "would add %v to PATH", dir)
+		path = append(path, dir)
+	}
+
+	names := make([]string, 0, len(options.Environment))
+	for name := range options.Environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []*installdb.EnvironmentChange
+	for _, name := range names {
+		value, err := expandString(options.Environment[name], nil)
+		if err != nil {
+			return path, changes, fmt.Errorf("could not expand environment variable %v: %w", name, err)
+		}
+
+		previous, hadPrevious, err := env.Get(name)
+		if err != nil {
+			return path, changes, err
+		}
+
+		if err := env.Set(name, value); err != nil {
+			return path, changes, fmt.Errorf("could not set environment variable %v: %w", name, err)
+		}
+
+		h.logChange("set %v to %v", "would set %v to %v", name, value)
+		changes = append(changes, &installdb.EnvironmentChange{Name: name, Value: value, Previous: previous, HadPrevious: hadPrevious})
+	}
+
+	return path, changes, nil
+}
+
+// revertEnvironment undoes the environment changes made when the given package was installed.
+// Variables that were changed since then are left alone.
+func revertEnvironment(h *host, record *installdb.Record) error {
+	if len(record.Path) == 0 && len(record.Environment) == 0 {
+		return nil
+	}
+
+	env, err := h.Environment(record.Scope == string(installer.ScopeUser))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range record.Path {
+		if err := environment.RemoveFromPath(env, dir); err != nil {
+			return fmt.Errorf("could not remove %v from PATH: %w", dir, err)
+		}
+
+		h.logChange("removed %v from PATH", "would remove %v from PATH", dir)
+	}
+
+	for _, change := range record.Environment {
+		current, ok, err := env.Get(change.Name)
+		if err != nil {
+			return err
+		}
+
+		if !ok || current != change.Value {
+			log.Printf("WARNING: leaving environment variable %v alone, since it was changed after installation", change.Name)
+			continue
+		}
+
+		if !change.HadPrevious {
+			if err := env.Delete(change.Name); err != nil {
+				return fmt.Errorf("could not delete environment variable %v: %w", change.Name, err)
+			}
+
+			h.logChange("deleted %v", "would delete %v", change.Name)
+			continue
+		}
+
+		if err := env.Set(change.Name, change.Previous); err != nil {
+			return fmt.Errorf("could not restore environment variable %v: %w", change.Name, err)
+		}
+
+		h.logChange("restored %v to %v", "would restore %v to %v", change.Name, change.Previous)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/just-install/just-install/pkg/cmd"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// runHooks runs the pre- or post-install commands of a package, in order, stopping at the first one
+// that fails. Their arguments can reference the installer, like the ones of custom installers.
+func runHooks(h *host, name string, stage string, hooks []*registry4.Hook, installerPath string, timeout time.Duration) error {
+	for i, hook := range hooks {
+		var args []string
+		for _, v := range hook.Arguments {
+			expanded, err := expandString(v, map[string]string{"installer": installerPath})
+			if err != nil {
+				return fmt.Errorf("could not expand %v command: %w", stage, err)
+			}
+
+			args = append(args, expanded)
+		}
+
+		runOptions, err := newRunOptions(h, name, fmt.Sprintf("%v-%v", stage, i+1), timeout)
+		if err != nil {
+			return err
+		}
+
+		// Hooks don't write a log where we'd like them to
+		runOptions.LogFile = ""
+
+		err = h.Runner.Run(runOptions, args...)
+
+		var cmdErr *cmd.Error
+		if errors.As(err, &cmdErr) && cmdErr.ExitCode >= 0 && hook.IsSuccess(cmdErr.ExitCode) {
+			log.Printf("%v exited with code %v, which is a success for this %v command", args[0], cmdErr.ExitCode, stage)
+		} else if err != nil {
+			return fmt.Errorf("%v command %v of %v failed: %w", stage, i+1, len(hooks), err)
+		} else if !hook.IsSuccess(0) && !h.DryRun {
+			return fmt.Errorf("%v command %v of %v failed: %v exited with code 0", stage, i+1, len(hooks), args[0])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+func TestRunHooks(t *testing.T) {
+	t.Setenv("JUST_INSTALL_DATA_DIR", t.TempDir())
+
+	hooks := []*registry4.Hook{
+		{Arguments: []string{"unblock", "{{.installer}}"}},
+		{Arguments: []string{"configure", "--quiet"}, SuccessCodes: []int{0, 3010}},
+		{Arguments: []string{"cleanup"}},
+	}
+
+	tests := []struct {
+		name     string
+		dryRun   bool
+		hooks    []*registry4.Hook
+		codes    []int
+		wantRuns int
+		wantErr  bool
+	}{
+		{"all succeed", false, hooks, []int{0, 0, 0}, 3, false},
+		{"custom success code", false, hooks, []int{0, 3010, 0}, 3, false},
+		{"stops at the first failure", false, hooks, []int{0, 1, 0}, 2, true},
+		{"exit code 0 is a failure", false, []*registry4.Hook{{Arguments: []string{"check"}, SuccessCodes: []int{1}}}, []int{0}, 1, true},
+		{"exit code 0 in dry runs", true, []*registry4.Hook{{Arguments: []string{"check"}, SuccessCodes: []int{1}}}, []int{0}, 1, false},
+		{"no hooks", false, nil, nil, 0, false},
+	}
+
+	for _, test := range tests {
+		runner := &exitCodeRunner{codes: test.codes}
+		h := newHost(test.dryRun)
+		h.Runner = runner
+
+		err := runHooks(h, "example", "post-install", test.hooks, "C:\\Temp\\setup.exe", time.Minute)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want error %v", test.name, err, test.wantErr)
+		}
+
+		if runner.runs != test.wantRuns {
+			t.Errorf("%v: ran %v commands, want %v", test.name, runner.runs, test.wantRuns)
+		}
+	}
+}
+
+func TestRunHooksExpandsInstaller(t *testing.T) {
+	t.Setenv("JUST_INSTALL_DATA_DIR", t.TempDir())
+
+	runner := &exitCodeRunner{codes: []int{0}}
+	h := newHost(false)
+	h.Runner = runner
+
+	hooks := []*registry4.Hook{{Arguments: []string{"unblock", "{{.installer}}", "literal"}}}
+	if err := runHooks(h, "example", "pre-install", hooks, "C:\\Temp\\setup.exe", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := [][]string{{"unblock", "C:\\Temp\\setup.exe", "literal"}}; !reflect.DeepEqual(runner.args, want) {
+		t.Errorf("got %q, want %q", runner.args, want)
+	}
+}
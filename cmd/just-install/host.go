@@ -0,0 +1,259 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ungerik/go-dry"
+	"github.com/urfave/cli/v2"
+
+	"github.com/just-install/just-install/pkg/cmd"
+	"github.com/just-install/just-install/pkg/environment"
+	"github.com/just-install/just-install/pkg/fetch"
+	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/shortcut"
+)
+
+// host carries out whatever installing and uninstalling packages changes on the machine. The host of
+// a dry run only logs what would be done.
+type host struct {
+	DryRun  bool
+	Fetcher fetcher
+	FS      fileSystem
+	Runner  cmd.Runner
+	Retry   retryPolicy
+}
+
+// newHost returns the host for real runs or dry runs, which doesn't retry installers.
+func newHost(dryRun bool) *host {
+	if dryRun {
+		return &host{true, dryFetcher{}, dryFileSystem{}, cmd.DryRunner{}, retryPolicy{}}
+	}
+
+	return &host{false, httpFetcher{}, osFileSystem{}, cmd.ExecRunner{}, retryPolicy{}}
+}
+
+// hostFromContext returns the host configured by the global command line options.
+func hostFromContext(c *cli.Context) *host {
+	ret := newHost(c.Bool("dry-run"))
+	ret.Retry = retryPolicy{Retries: c.Int("retries"), Delay: c.Duration("retry-delay")}
+
+	return ret
+}
+
+// maxRetryDelay caps the delay between two attempts to run an installer.
+const maxRetryDelay = 5 * time.Minute
+
+// retryPolicy says how installers that exit with a retryable code, such as Windows Installer being
+// busy with another installation, are run again.
+type retryPolicy struct {
+	Retries int           // How many times to run the installer again, at most
+	Delay   time.Duration // Wait before the first retry, doubled after each retry up to maxRetryDelay
+}
+
+// delay returns the wait before the given retry, starting from 1.
+func (p retryPolicy) delay(retry int) time.Duration {
+	ret := p.Delay
+	for i := 1; i < retry && ret < maxRetryDelay; i++ {
+		ret *= 2
+	}
+
+	if ret > maxRetryDelay {
+		return maxRetryDelay
+	}
+
+	return ret
+}
+
+// Environment returns the writer of the persistent environment of the machine or, if user is true,
+// of the current user. In dry runs, the environment is read but left unchanged.
+func (h *host) Environment(user bool) (environment.Writer, error) {
+	ret, err := environment.Default(user)
+	if err != nil || !h.DryRun {
+		return ret, err
+	}
+
+	return dryEnvironment{ret}, nil
+}
+
+// logChange logs a change made to the machine, or that would be made in dry runs.
+func (h *host) logChange(doing string, wouldDo string, args ...interface{}) {
+	if h.DryRun {
+		log.Printf(wouldDo, args...)
+	} else {
+		log.Printf(doing, args...)
+	}
+}
+
+// fetcher downloads installers.
+type fetcher interface {
+	Fetch(resource string, options *fetch.Options) (string, error)
+}
+
+// fileSystem makes the changes to the file system needed to install and uninstall packages.
+// Missing parent directories are created as needed.
+type fileSystem interface {
+	MkdirAll(path string) error
+	Copy(src string, dst string) error
+	Extract(path string, kind installer.ContainerKind, dest string, options *installer.ExtractOptions) error
+	CreateShortcut(s *shortcut.Shortcut, location string) error
+	Remove(path string) error // Removes a file or an empty directory, it's not an error if it's missing
+	RemoveAll(path string) error
+	Rename(src string, dst string) error
+}
+
+type httpFetcher struct{}
+
+func (httpFetcher) Fetch(resource string, options *fetch.Options) (string, error) {
+	return fetch.Fetch(resource, options)
+}
+
+// dryFetcher only guesses where resources would be downloaded, since the actual file name may be
+// chosen by the server.
+type dryFetcher struct{}
+
+func (dryFetcher) Fetch(resource string, options *fetch.Options) (string, error) {
+	if dry.FileExists(resource) {
+		return resource, nil
+	}
+
+	parsedURL, err := url.Parse(resource)
+	if err != nil {
+		return "", err
+	}
+
+	if parsedURL.Scheme == "file" {
+		return parsedURL.Path, nil
+	}
+
+	dest := filepath.Join(options.Destination, filepath.Base(parsedURL.Path))
+	if dry.FileExists(dest) && !options.Overwrite {
+		log.Println("would use", dest, "already downloaded from", resource)
+	} else {
+		log.Println("would fetch", resource, "to", dest)
+	}
+
+	return dest, nil
+}
+
+type osFileSystem struct{}
+
+func (osFileSystem) MkdirAll(path string) error {
+	return os.MkdirAll(path, os.ModePerm)
+}
+
+func (fs osFileSystem) Copy(src string, dst string) error {
+	if err := fs.MkdirAll(filepath.Dir(dst)); err != nil {
+		return err
+	}
+
+	log.Println("copying", src, "to", dst)
+	return dry.FileCopy(src, dst)
+}
+
+func (osFileSystem) Extract(path string, kind installer.ContainerKind, dest string, options *installer.ExtractOptions) error {
+	log.Println("extracting", path, "to", dest)
+	return installer.Extract(path, kind, dest, options)
+}
+
+func (osFileSystem) CreateShortcut(s *shortcut.Shortcut, location string) error {
+	log.Println("creating shortcut to", s.Target, "in", location)
+	return s.Save(location)
+}
+
+func (osFileSystem) Remove(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	log.Println("removing", path)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (osFileSystem) RemoveAll(path string) error {
+	log.Println("removing", path)
+	return os.RemoveAll(path)
+}
+
+func (osFileSystem) Rename(src string, dst string) error {
+	log.Println("moving", src, "to", dst)
+	return os.Rename(src, dst)
+}
+
+type dryFileSystem struct{}
+
+func (dryFileSystem) MkdirAll(path string) error {
+	return nil
+}
+
+func (dryFileSystem) Copy(src string, dst string) error {
+	log.Println("would copy", src, "to", dst)
+	return nil
+}
+
+func (dryFileSystem) Extract(path string, kind installer.ContainerKind, dest string, options *installer.ExtractOptions) error {
+	if kind == "" {
+		kind = "archive"
+	}
+
+	log.Printf("would extract %v (%v) to %v", path, kind, dest)
+	return nil
+}
+
+func (dryFileSystem) CreateShortcut(s *shortcut.Shortcut, location string) error {
+	log.Println("would create shortcut to", s.Target, "in", location)
+	return nil
+}
+
+func (dryFileSystem) Remove(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	log.Println("would remove", path)
+	return nil
+}
+
+func (dryFileSystem) RemoveAll(path string) error {
+	log.Println("would remove", path)
+	return nil
+}
+
+func (dryFileSystem) Rename(src string, dst string) error {
+	log.Println("would move", src, "to", dst)
+	return nil
+}
+
+type dryEnvironment struct {
+	environment.Writer
+}
+
+func (dryEnvironment) Set(name string, value string) error {
+	return nil
+}
+
+func (dryEnvironment) Delete(name string) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/just-install/just-install/pkg/cmd"
+	"github.com/just-install/just-install/pkg/installer"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		delay time.Duration
+		retry int
+		want  time.Duration
+	}{
+		{30 * time.Second, 1, 30 * time.Second},
+		{30 * time.Second, 2, time.Minute},
+		{30 * time.Second, 3, 2 * time.Minute},
+		{30 * time.Second, 4, 4 * time.Minute},
+		{30 * time.Second, 5, maxRetryDelay},
+		{30 * time.Second, 1000, maxRetryDelay},
+		{10 * time.Minute, 1, maxRetryDelay},
+		{0, 3, 0},
+	}
+
+	for _, test := range tests {
+		if got := (retryPolicy{Retries: 10, Delay: test.delay}).delay(test.retry); got != test.want {
+			t.Errorf("delay %v, retry %v: got %v, want %v", test.delay, test.retry, got, test.want)
+		}
+	}
+}
+
+// exitCodeRunner is a cmd.Runner whose commands exit with the given codes in turn.
+type exitCodeRunner struct {
+	codes []int
+	runs  int
+	args  [][]string // Command lines run so far
+}
+
+func (r *exitCodeRunner) Run(options *cmd.Options, args ...string) error {
+	code := r.codes[r.runs]
+	r.runs++
+	r.args = append(r.args, args)
+
+	if code == 0 {
+		return nil
+	}
+
+	return &cmd.Error{Args: args, Err: errors.New("exit status"), ExitCode: code}
+}
+
+func TestRunInstallerRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  int
+		codes    []int
+		wantRuns int
+		want     installer.ExitStatus
+	}{
+		{"success", 3, []int{0}, 1, installer.ExitSuccess},
+		{"failure is not retried", 3, []int{1603}, 1, installer.ExitFailure},
+		{"retries disabled", 0, []int{1618}, 1, installer.ExitRetryable},
+		{"succeeds on retry", 3, []int{1618, 1618, 0}, 3, installer.ExitSuccess},
+		{"reboot required on retry", 3, []int{1618, 3010}, 2, installer.ExitSuccessRebootRequired},
+		{"gives up", 2, []int{1618, 1618, 1618}, 3, installer.ExitRetryable},
+	}
+
+	for _, test := range tests {
+		runner := &exitCodeRunner{codes: test.codes}
+		h := &host{Runner: runner, Retry: retryPolicy{Retries: test.retries, Delay: time.Nanosecond}}
+
+		got, err := runInstaller(h, installer.MSI, &cmd.Options{}, "setup.msi")
+		if got != test.want {
+			t.Errorf("%v: got %v (%v), want %v", test.name, got, err, test.want)
+		}
+
+		wantErr := test.want == installer.ExitFailure || test.want == installer.ExitRetryable
+		if (err != nil) != wantErr {
+			t.Errorf("%v: error = %v, want error %v", test.name, err, wantErr)
+		} else if err != nil && exitStatusOf(err) != test.want {
+			t.Errorf("%v: error carries status %v, want %v", test.name, exitStatusOf(err), test.want)
+		}
+
+		if runner.runs != test.wantRuns {
+			t.Errorf("%v: installer ran %v times, want %v", test.name, runner.runs, test.wantRuns)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli/v2"
 
@@ -45,14 +46,196 @@ func main() {
 		Name:   "clean",
 		Usage:  "Remove caches and temporary files",
 		Action: handleCleanAction,
+	}, {
+		Name:      "hold",
+		Usage:     "Prevent installed packages from being upgraded",
+		ArgsUsage: "<package>...",
+		Action:    handleHoldAction,
+	}, {
+		Name:      "info",
+		Usage:     "Show details about a package",
+		ArgsUsage: "<package>",
+		Action:    handleInfoAction,
 	}, {
 		Name:   "list",
 		Usage:  "List all known packages",
 		Action: handleListAction,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "installed",
+				Usage: "List the packages installed by just-install instead",
+			},
+		},
+	}, {
+		Name:   "outdated",
+		Usage:  "List installed packages for which a newer version is available",
+		Action: handleOutdatedAction,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format (text or json)",
+				Value: "text",
+			},
+		},
+	}, {
+		Name:  "registry",
+		Usage: "Tools to maintain registry files",
+		Subcommands: []*cli.Command{{
+			Name:      "add",
+			Usage:     "Download an installer, detect its kind and scaffold a registry entry for it",
+			ArgsUsage: "<installer URL>",
+			Action:    handleRegistryAddAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "arch",
+					Usage: "Architecture of the installer (x86 or x86_64)",
+					Value: "x86",
+				}, &cli.StringFlag{
+					Name:  "file",
+					Usage: "Add the entry to the specified registry file instead of printing it",
+				}, &cli.StringFlag{
+					Name:  "name",
+					Usage: "Name of the package, guessed from the URL if missing",
+				}, &cli.StringFlag{
+					Name:  "version",
+					Usage: "Version of the package, guessed from the URL if missing",
+				},
+			},
+		}, {
+			Name:      "bump",
+			Usage:     "Update the version of a package, and the checksums of its installers, in a registry file",
+			ArgsUsage: "<package> [version]",
+			Action:    handleRegistryBumpAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "file",
+					Usage: "Registry file to update",
+				},
+			},
+		}, {
+			Name:      "checkver",
+			Usage:     "Check the upstream version of packages and report outdated entries",
+			ArgsUsage: "[package]...",
+			Action:    handleRegistryCheckverAction,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "all",
+					Usage: "Also show packages that are up to date",
+				}, &cli.StringFlag{
+					Name:  "file",
+					Usage: "Check the specified registry file instead of the current registry",
+				}, &cli.StringFlag{
+					Name:  "format",
+					Usage: "Output format (text or json)",
+					Value: "text",
+				},
+			},
+		}, {
+			Name:      "diff",
+			Usage:     "Show the differences between two registry files",
+			ArgsUsage: "<old registry file> <new registry file>",
+			Action:    handleRegistryDiffAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Output format (text or json)",
+					Value: "text",
+				},
+			},
+		}, {
+			Name:      "convert",
+			Usage:     "Convert a registry file between JSON, YAML and TOML",
+			ArgsUsage: "<registry file> [output file]",
+			Action:    handleRegistryConvertAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "to",
+					Usage: "Output format (json, toml or yaml), inferred from the output file extension if missing",
+				},
+			},
+		}, {
+			Name:      "fmt",
+			Usage:     "Rewrite registry files in the canonical format",
+			ArgsUsage: "<registry file>...",
+			Action:    handleRegistryFmtAction,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "check",
+					Usage: "Only list the files that are not formatted canonically, failing if there are any",
+				},
+			},
+		}, {
+			Name:      "lint",
+			Usage:     "Check a registry file for common mistakes",
+			ArgsUsage: "[registry file]",
+			Action:    handleRegistryLintAction,
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "disable",
+					Usage: "Do not run the specified rule (can be repeated)",
+				}, &cli.StringFlag{
+					Name:  "format",
+					Usage: "Output format (text or json)",
+					Value: "text",
+				}, &cli.BoolFlag{
+					Name:  "list-rules",
+					Usage: "List the available rules and exit",
+				},
+			},
+		}, {
+			Name:      "migrate",
+			Usage:     "Convert a v4 registry file to the v5 format",
+			ArgsUsage: "<v4 registry file>",
+			Action:    handleRegistryMigrateAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Aliases: []string{"o"},
+					Name:    "output",
+					Usage:   "Write the migrated registry to the specified file instead of standard output",
+				}, &cli.StringFlag{
+					Name:  "to",
+					Usage: "Output format (json, toml or yaml), inferred from the output file extension if missing",
+				}, &cli.StringFlag{
+					Name:  "schema",
+					Usage: "Value of the \"$schema\" field of the migrated registry",
+				},
+			},
+		}},
+	}, {
+		Name:      "uninstall",
+		Usage:     "Uninstall packages installed by just-install",
+		ArgsUsage: "<package>...",
+		Action:    handleUninstallAction,
+	}, {
+		Name:      "unhold",
+		Usage:     "Allow held packages to be upgraded again",
+		ArgsUsage: "<package>...",
+		Action:    handleUnholdAction,
 	}, {
 		Name:   "update",
-		Usage:  "Update the registry",
+		Usage:  "Update the registry and show what changed",
 		Action: handleUpdateAction,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Format of the change summary (text or json)",
+				Value: "text",
+			}, &cli.BoolFlag{
+				Name:  "rollback",
+				Usage: "Go back to the previously downloaded registry instead of downloading a new one",
+			},
+		},
+	}, {
+		Name:      "upgrade",
+		Usage:     "Upgrade installed packages to the version in the registry",
+		ArgsUsage: "[package]...",
+		Action:    handleUpgradeAction,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "Upgrade all installed packages",
+			},
+		},
 	}}
 
 	app.Flags = []cli.Flag{
@@ -64,6 +247,9 @@ func main() {
 			Aliases: []string{"d"},
 			Name:    "download-only",
 			Usage:   "Only download packages, do not install them",
+		}, &cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Print what installing, upgrading or uninstalling would do, without doing it",
 		}, &cli.BoolFlag{
 			Aliases: []string{"i"},
 			Name:    "ignore-cache",
@@ -71,7 +257,7 @@ func main() {
 		}, &cli.StringFlag{
 			Aliases: []string{"l"},
 			Name:    "lang",
-			Usage:   "Install apps in the specified language",
+			Usage:   "Install apps in the specified language(s), comma separated, instead of the system one",
 		}, &cli.BoolFlag{
 			Aliases: []string{"no-progress"},
 			Name:    "noprogress",
@@ -80,10 +266,25 @@ func main() {
 			Aliases: []string{"r"},
 			Name:    "registry",
 			Usage:   "Use the specified registry file",
+		}, &cli.IntFlag{
+			Name:  "retries",
+			Usage: "Run installers again, up to the given number of times, if they fail because another installation is in progress",
+			Value: 5,
+		}, &cli.DurationFlag{
+			Name:  "retry-delay",
+			Usage: "Wait before running an installer again, doubled after each retry",
+			Value: 30 * time.Second,
+		}, &cli.StringFlag{
+			Name:  "scope",
+			Usage: "Install packages for the current \"user\" or for the whole \"machine\", if supported by their installers",
 		}, &cli.BoolFlag{
 			Aliases: []string{"s"},
 			Name:    "shim",
 			Usage:   "Create shims only (if exeproxy is installed)",
+		}, &cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Kill installers still running after the given time, unless the package says otherwise (0 to wait forever)",
+			Value: time.Hour,
 		},
 	}
 
@@ -0,0 +1,74 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/just-install/just-install/internal/atomicfile"
+	"github.com/just-install/just-install/pkg/dataformat"
+)
+
+// marshalJSON encodes the given value as canonical JSON.
+func marshalJSON(v interface{}) ([]byte, error) {
+	return dataformat.Marshal(v, dataformat.JSON)
+}
+
+// writeOutput writes the given data to the file at the given path, or to standard output if the
+// path is empty or "-".
+func writeOutput(path string, b []byte) error {
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+
+	if err := atomicfile.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("could not write %v: %w", path, err)
+	}
+
+	return nil
+}
+
+// outputFormat returns the data format to use when writing to the given path: the explicitly
+// requested one if given, otherwise the one matching the file extension, otherwise JSON.
+func outputFormat(path string, requested string) (dataformat.Format, error) {
+	if requested != "" {
+		format := dataformat.Format(requested)
+		if !format.IsValid() {
+			return "", fmt.Errorf("unknown format: %v", requested)
+		}
+
+		return format, nil
+	}
+
+	if format, ok := dataformat.FromExtension(path); ok {
+		return format, nil
+	}
+
+	return dataformat.JSON, nil
+}
+
+// readDocument reads the file at the given path and converts it to JSON, whatever its format.
+func readDocument(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataformat.ToJSON(b, dataformat.Detect(path, b))
+}
@@ -16,53 +16,203 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ungerik/go-dry"
 	"github.com/urfave/cli/v2"
 
 	"github.com/just-install/just-install/pkg/fetch"
+	"github.com/just-install/just-install/pkg/generations"
 	"github.com/just-install/just-install/pkg/paths"
 	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/registry5"
 )
 
 const registryURL = "https://just-install.github.io/registry/just-install-v4.json"
 
+// registryGenerations is the number of known good copies of the registry that are kept around.
+const registryGenerations = 3
+
+// loadRegistry loads the registry, downloading it again if the local copy is older than a day or if
+// force is true. If the download fails, or yields something that is not a valid registry, the newest
+// known good copy is used instead.
 func loadRegistry(c *cli.Context, force bool, progress bool) (*registry4.Registry, error) {
-	src := registryURL
-	dst, dstErr := paths.TempFileCreate("registry.json")
-	if dstErr != nil {
-		return nil, fmt.Errorf("could not create temporary directory to hold registry file: %w", dstErr)
+	src, dst, err := registryPaths(c)
+	if err != nil {
+		return nil, err
 	}
 
-	if c.IsSet("registry") {
-		src = c.String("registry")
-		dst, dstErr = paths.TempFileCreate("registry-custom.json")
-		if dstErr != nil {
-			return nil, fmt.Errorf("could not create temporary directory to hold custom registry file: %w", dstErr)
+	store := registryStore(dst)
+
+	fresh := dry.FileExists(dst) && dry.FileTimeModified(dst).After(time.Now().Add(-24*time.Hour))
+	if fresh && !force {
+		ret, err := registry5.LoadCompatible(dst)
+		if err == nil {
+			return ret, nil
 		}
+
+		log.Println("WARNING: the local copy of the registry is damaged, downloading it again:", err)
+	}
+
+	ret, err := downloadRegistry(src, store, progress)
+	if err == nil {
+		return ret, nil
+	}
+
+	log.Println("WARNING: could not update the registry:", err)
+
+	ret, fallbackErr := loadLastKnownGoodRegistry(store)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("error obtaining registry: %w (%v)", err, fallbackErr)
+	}
+
+	return ret, nil
+}
+
+// updateRegistry downloads the registry, replacing the local copy. Unlike loadRegistry, it doesn't
+// fall back to a previous copy if the download fails.
+func updateRegistry(c *cli.Context, progress bool) (*registry4.Registry, error) {
+	src, dst, err := registryPaths(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return downloadRegistry(src, registryStore(dst), progress)
+}
+
+// downloadRegistry downloads the registry next to the current local copy and, only if it is valid,
+// atomically replaces the latter. Registries that are local files are loaded in place instead.
+func downloadRegistry(src string, store *generations.Store, progress bool) (*registry4.Registry, error) {
+	candidate := store.Path + ".new"
+
+	path, err := fetch.Fetch(src, &fetch.Options{Destination: candidate, Overwrite: true, Progress: progress})
+	if err != nil {
+		return nil, err
+	}
+
+	if path != candidate {
+		return registry5.LoadCompatible(path)
+	}
+
+	ret, err := registry5.LoadCompatible(candidate)
+	if err != nil {
+		os.Remove(candidate)
+		return nil, fmt.Errorf("downloaded registry is not valid: %w", err)
 	}
 
-	if force && dry.FileExists(dst) {
-		if err := os.Remove(dst); err != nil {
-			return nil, fmt.Errorf("could not delete %v due to %w", dst, err)
+	if err := store.Commit(candidate); err != nil {
+		return nil, fmt.Errorf("could not save registry: %w", err)
+	}
+
+	return ret, nil
+}
+
+// loadLastKnownGoodRegistry loads the newest copy of the registry that is still valid.
+func loadLastKnownGoodRegistry(store *generations.Store) (*registry4.Registry, error) {
+	if dry.FileExists(store.Path) {
+		if ret, err := registry5.LoadCompatible(store.Path); err == nil {
+			log.Println("WARNING: using the registry downloaded on", dry.FileTimeModified(store.Path).Format(time.RFC1123))
+			return ret, nil
 		}
 	}
 
-	download := !dry.FileExists(dst)
-	download = download || dry.FileTimeModified(dst).Before(time.Now().Add(-24*time.Hour))
-	if !download {
-		ret, err := registry4.Load(dst)
-		return ret, err
+	list, err := store.List()
+	if err != nil {
+		return nil, err
 	}
 
-	dst, err := fetch.Fetch(src, &fetch.Options{Destination: dst, Progress: progress})
+	for _, generation := range list {
+		ret, err := registry5.LoadCompatible(generation.Path)
+		if err != nil {
+			continue
+		}
+
+		if err := store.Restore(generation); err != nil {
+			log.Println("WARNING: could not restore the registry downloaded on", generation.Time.Local().Format(time.RFC1123))
+		}
+
+		log.Println("WARNING: using the registry downloaded on", generation.Time.Local().Format(time.RFC1123))
+		return ret, nil
+	}
+
+	return nil, errors.New("no usable copy of the registry is available")
+}
+
+// registryStore returns the store holding the local copy of the registry at the given path, along
+// with its previous generations.
+func registryStore(path string) *generations.Store {
+	return &generations.Store{Path: path, Keep: registryGenerations}
+}
+
+// registryPaths returns the location of the registry to use, either the default one or the one
+// given on the command line, and the path where its local copy is kept.
+func registryPaths(c *cli.Context) (string, string, error) {
+	if c.IsSet("registry") {
+		dst, err := paths.TempFileCreate("registry-custom.json")
+		if err != nil {
+			return "", "", fmt.Errorf("could not create temporary directory to hold custom registry file: %w", err)
+		}
+
+		return c.String("registry"), dst, nil
+	}
+
+	dst, err := paths.TempFileCreate("registry.json")
 	if err != nil {
-		return nil, fmt.Errorf("error obtaining registry: %w", err)
+		return "", "", fmt.Errorf("could not create temporary directory to hold registry file: %w", err)
+	}
+
+	return registryURL, dst, nil
+}
+
+// resolvePackage looks up the package with the given name, logging whether it was found through an
+// alias, whether it is deprecated and, if it could not be found, which packages have similar names.
+func resolvePackage(registry *registry4.Registry, name string) (*registry4.Match, bool) {
+	match, ok := registry.Lookup(name)
+	if !ok {
+		log.Println("WARNING: unknown package", name)
+
+		if suggestions := registry.Suggest(name, 3); len(suggestions) > 0 {
+			log.Printf("did you mean %v?", strings.Join(suggestions, ", "))
+		}
+
+		return nil, false
+	}
+
+	if match.Name != name {
+		log.Printf("%v resolves to %v", name, match.Name)
+	}
+
+	if notice := deprecationNotice(match.Package); notice != "" {
+		log.Printf("WARNING: %v is deprecated: %v", match.Name, notice)
+	}
+
+	return match, true
+}
+
+// deprecationNotice returns a human readable description of the deprecation status of the given
+// package, or an empty string if the package is not deprecated.
+func deprecationNotice(pkg *registry4.Package) string {
+	if pkg.Deprecated == nil {
+		return ""
+	}
+
+	var parts []string
+	if pkg.Deprecated.Message != "" {
+		parts = append(parts, pkg.Deprecated.Message)
+	}
+
+	if pkg.Deprecated.Replacement != "" {
+		parts = append(parts, fmt.Sprintf("use %v instead", pkg.Deprecated.Replacement))
+	}
+
+	if len(parts) == 0 {
+		return "no longer maintained"
 	}
 
-	ret, err := registry4.Load(dst)
-	return ret, err
+	return strings.Join(parts, ", ")
 }
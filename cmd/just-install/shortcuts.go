@@ -0,0 +1,166 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/just-install/just-install/pkg/cmd"
+	"github.com/just-install/just-install/pkg/installdb"
+	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/shortcut"
+)
+
+// createShortcuts creates the shortcuts of a package in the Start Menu or on the desktop of all users
+// or, for the user scope, of the current user. It returns the paths of the shortcuts it created,
+// even if it fails halfway.
+func createShortcuts(h *host, options *registry4.Options, scope installer.Scope) ([]string, error) {
+	if options == nil {
+		return nil, nil
+	}
+
+	var ret []string
+
+	for _, s := range options.Shortcuts {
+		location, link, err := expandShortcut(s, scope)
+		if err != nil {
+			return ret, fmt.Errorf("shortcut %v: %w", s.Name, err)
+		}
+
+		if err := h.FS.CreateShortcut(link, location); err != nil {
+			return ret, fmt.Errorf("could not create shortcut: %w", err)
+		}
+
+		ret = append(ret, location)
+	}
+
+	return ret, nil
+}
+
+// expandShortcut expands the templates of a shortcut, returning where its .lnk file goes and what it
+// contains.
+func expandShortcut(s *registry4.Shortcut, scope installer.Scope) (string, *shortcut.Shortcut, error) {
+	name, folder := s.Name, s.Folder
+	link := &shortcut.Shortcut{
+		Target:           s.Target,
+		WorkingDirectory: s.WorkingDirectory,
+		Icon:             s.Icon,
+		IconIndex:        s.IconIndex,
+		Description:      s.Description,
+	}
+
+	for _, field := range []*string{&name, &folder, &link.Target, &link.WorkingDirectory, &link.Icon, &link.Description} {
+		expanded, err := expandString(*field, nil)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not expand string template: %w", err)
+		}
+
+		*field = expanded
+	}
+
+	var args []string
+	for _, v := range s.Arguments {
+		arg, err := expandString(v, nil)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not expand argument string template: %w", err)
+		}
+
+		args = append(args, arg)
+	}
+	link.Arguments = cmd.CommandLine(args)
+
+	// The registry is validated before templates are expanded, check again what they expanded to.
+	if !registry4.IsShortcutName(name) {
+		return "", nil, fmt.Errorf("name must be a file name, without folders: %v", name)
+	}
+
+	if !registry4.IsShortcutFolder(folder) {
+		return "", nil, fmt.Errorf("folder must be a relative path within the Start Menu: %v", folder)
+	}
+
+	var dir string
+	var err error
+	if s.Desktop {
+		dir, err = shortcut.DesktopDir(scope == installer.ScopeUser)
+	} else {
+		dir, err = shortcut.StartMenuDir(scope == installer.ScopeUser)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if folder != "" {
+		dir = filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(folder, "\\", "/")))
+	}
+
+	return filepath.Join(dir, name+".lnk"), link, nil
+}
+
+// removeShortcut removes a shortcut created by createShortcuts, along with the Start Menu folders
+// that held nothing else.
+func removeShortcut(h *host, path string, scope installer.Scope) error {
+	if err := h.FS.Remove(path); err != nil {
+		return err
+	}
+
+	startMenu, err := shortcut.StartMenuDir(scope == installer.ScopeUser)
+	if err != nil {
+		return nil
+	}
+
+	for dir := filepath.Dir(path); isBelow(dir, startMenu); dir = filepath.Dir(dir) {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			break
+		}
+
+		if err := h.FS.Remove(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeStaleShortcuts removes the shortcuts of the previous version of an upgraded package that the
+// new version didn't create again.
+func removeStaleShortcuts(h *host, previous *installdb.Record, current []string) {
+	kept := make(map[string]bool, len(current))
+	for _, path := range current {
+		kept[strings.ToLower(path)] = true
+	}
+
+	for _, path := range previous.Shortcuts {
+		if kept[strings.ToLower(path)] {
+			continue
+		}
+
+		if err := removeShortcut(h, path, installer.Scope(previous.Scope)); err != nil {
+			log.Printf("WARNING: could not remove shortcut of the previous version: %v", err)
+		}
+	}
+}
+
+// isBelow returns whether path is a subdirectory of dir.
+func isBelow(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,64 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/shortcut"
+)
+
+func TestExpandShortcut(t *testing.T) {
+	t.Setenv("JUST_INSTALL_DATA_DIR", t.TempDir())
+	t.Setenv("SHORTCUT_NAME", "..\\..\\Startup\\Example")
+	t.Setenv("SHORTCUT_FOLDER", "..")
+
+	startMenu, err := shortcut.StartMenuDir(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		shortcut registry4.Shortcut
+		want     string
+	}{
+		{"plain", registry4.Shortcut{Name: "Example", Target: "example.exe"}, filepath.Join(startMenu, "Example.lnk")},
+		{"folder", registry4.Shortcut{Name: "Example", Folder: "Tools\\Example", Target: "example.exe"}, filepath.Join(startMenu, "Tools", "Example", "Example.lnk")},
+		{"name expanding to a path", registry4.Shortcut{Name: "{{.SHORTCUT_NAME}}", Target: "example.exe"}, ""},
+		{"folder expanding outside", registry4.Shortcut{Name: "Example", Folder: "Tools\\{{.SHORTCUT_FOLDER}}\\{{.SHORTCUT_FOLDER}}", Target: "example.exe"}, ""},
+	}
+
+	for _, test := range tests {
+		got, _, err := expandShortcut(&test.shortcut, installer.ScopeMachine)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%v: got %v, want an error", test.name, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
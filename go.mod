@@ -3,9 +3,14 @@ module github.com/just-install/just-install
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/blang/semver/v4 v4.0.0
+	github.com/bodgit/sevenzip v1.1.1
 	github.com/cheggaaa/pb/v3 v3.0.5
 	github.com/gotopkg/mslnk v0.0.0-20200220201931-035af8d22c8a
+	github.com/klauspost/compress v1.15.1
+	github.com/ulikunitz/xz v0.5.10
 	github.com/ungerik/go-dry v0.0.0-20180411133923-654ae31114c8
 	github.com/urfave/cli/v2 v2.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
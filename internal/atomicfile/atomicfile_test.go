@@ -0,0 +1,88 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package atomicfile
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.json")
+
+	for _, contents := range []string{"first", "second, longer than the first", "third"} {
+		if err := WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != contents {
+			t.Errorf("got %q, want %q", got, contents)
+		}
+	}
+
+	// No temporary file is left behind
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("got %v files, want 1", len(entries))
+	}
+}
+
+func TestWriteFileMissingDirectory(t *testing.T) {
+	if err := WriteFile(filepath.Join(t.TempDir(), "missing", "file"), nil, 0644); err == nil {
+		t.Error("wrote to a missing directory")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	if err := ioutil.WriteFile(src, []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(dst, []byte("previous contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Copy(src, dst, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := ioutil.ReadFile(dst); err != nil || string(got) != "contents" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	if err := Copy(filepath.Join(dir, "missing"), dst, 0644); err == nil {
+		t.Error("copied a missing file")
+	}
+
+	if got, err := ioutil.ReadFile(dst); err != nil || string(got) != "contents" {
+		t.Errorf("failed copy changed the destination: %q, %v", got, err)
+	}
+}
@@ -19,6 +19,7 @@ package architecture
 
 // Supported architectures.
 const (
+	ARM64  = "arm64"
 	X86    = "x86"
 	X86_64 = "x86_64"
 )
@@ -26,7 +27,7 @@ const (
 // IsValid returns whether the given string can be converted to a valid Architecture.
 func IsValid(s string) bool {
 	switch s {
-	case X86, X86_64:
+	case ARM64, X86, X86_64:
 		return true
 	default:
 		return false
@@ -35,5 +36,5 @@ func IsValid(s string) bool {
 
 // Architectures returns all the supported architectures.
 func Architectures() []string {
-	return []string{X86, X86_64}
+	return []string{ARM64, X86, X86_64}
 }
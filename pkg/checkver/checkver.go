@@ -0,0 +1,173 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package checkver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/just-install/just-install/pkg/fetch"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// GitHubAPI is the base URL of the GitHub API. It defaults to the value of the GITHUB_API_URL
+// environment variable, when set, so that it can point to GitHub Enterprise or a test server.
+var GitHubAPI = gitHubAPIFromEnvironment()
+
+// Latest returns the latest upstream version of a package, as described by the given checkver
+// section.
+func Latest(checkver *registry4.Checkver) (string, error) {
+	if checkver == nil {
+		return "", errors.New("package has no \"checkver\" section")
+	}
+
+	switch {
+	case checkver.GitHub != "":
+		return latestGitHubRelease(checkver)
+	case checkver.URL != "":
+		return latestFromURL(checkver)
+	default:
+		return "", errors.New("\"checkver\" must contain either \"github\" or \"url\"")
+	}
+}
+
+// gitHubAPIFromEnvironment returns the base URL of the GitHub API taken from the environment,
+// falling back to the public one.
+func gitHubAPIFromEnvironment() string {
+	if ret := os.Getenv("GITHUB_API_URL"); ret != "" {
+		return ret
+	}
+
+	return "https://api.github.com"
+}
+
+// latestGitHubRelease returns the tag of the latest release of a GitHub repository, without the
+// customary "v" prefix (unless a regular expression is given, in which case it is used instead).
+func latestGitHubRelease(checkver *registry4.Checkver) (string, error) {
+	if strings.Count(checkver.GitHub, "/") != 1 {
+		return "", fmt.Errorf("invalid GitHub repository: %v", checkver.GitHub)
+	}
+
+	b, err := fetch.Read(strings.TrimRight(GitHubAPI, "/")+"/repos/"+checkver.GitHub+"/releases/latest", &fetch.Options{
+		HTTP: fetch.HTTPOptions{Headers: map[string]string{"Accept": "application/vnd.github.v3+json"}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	tag, err := lookupJSONPath(b, "tag_name")
+	if err != nil {
+		return "", err
+	}
+
+	if checkver.Regex != "" {
+		return match(checkver.Regex, tag)
+	}
+
+	return strings.TrimPrefix(tag, "v"), nil
+}
+
+// latestFromURL extracts the latest version from the document at the checkver URL.
+func latestFromURL(checkver *registry4.Checkver) (string, error) {
+	if checkver.JSONPath == "" && checkver.Regex == "" {
+		return "", errors.New("\"checkver\" must contain \"jsonPath\", \"regex\" or both when \"url\" is given")
+	}
+
+	b, err := fetch.Read(checkver.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	text := string(b)
+
+	if checkver.JSONPath != "" {
+		text, err = lookupJSONPath(b, checkver.JSONPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if checkver.Regex != "" {
+		return match(checkver.Regex, text)
+	}
+
+	return text, nil
+}
+
+// match applies the given regular expression to the given text, returning its first capturing
+// group or, if there is none, the whole match.
+func match(expr string, text string) (string, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	m := re.FindStringSubmatch(text)
+	if m == nil {
+		return "", fmt.Errorf("regular expression %v did not match", expr)
+	}
+
+	if len(m) > 1 {
+		return m[1], nil
+	}
+
+	return m[0], nil
+}
+
+// lookupJSONPath returns the value at the given dot-separated path (e.g. "data.0.version") in the
+// given JSON document, formatted as a string. A leading "$." is accepted and ignored.
+func lookupJSONPath(b []byte, path string) (string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", fmt.Errorf("could not decode JSON document: %w", err)
+	}
+
+	current := doc
+	for _, key := range strings.Split(strings.TrimPrefix(path, "$."), ".") {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, ok := value[key]
+			if !ok {
+				return "", fmt.Errorf("key %v not found while looking up %v", key, path)
+			}
+
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(value) {
+				return "", fmt.Errorf("invalid index %v while looking up %v", key, path)
+			}
+
+			current = value[index]
+		default:
+			return "", fmt.Errorf("cannot look up %v in a scalar value while looking up %v", key, path)
+		}
+	}
+
+	switch value := current.(type) {
+	case string:
+		return value, nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("value at %v is not a string or number", path)
+	}
+}
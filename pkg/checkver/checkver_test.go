@@ -0,0 +1,99 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package checkver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+func TestLatest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/files/example-2.4.1.exe">Download version 2.4.1</a>`))
+	})
+	mux.HandleFunc("/api.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"version": "3.1.0"}, {"version": "3.0.9"}], "build": 42}`))
+	})
+	mux.HandleFunc("/repos/example/example/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "v1.2.3"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	defer func(previous string) { GitHubAPI = previous }(GitHubAPI)
+	GitHubAPI = server.URL
+
+	tests := []struct {
+		name     string
+		checkver *registry4.Checkver
+		want     string
+	}{
+		{"regex", &registry4.Checkver{URL: server.URL + "/download.html", Regex: `example-([\d.]+)\.exe`}, "2.4.1"},
+		{"json path", &registry4.Checkver{URL: server.URL + "/api.json", JSONPath: "data.0.version"}, "3.1.0"},
+		{"json path with prefix", &registry4.Checkver{URL: server.URL + "/api.json", JSONPath: "$.data.1.version"}, "3.0.9"},
+		{"json number", &registry4.Checkver{URL: server.URL + "/api.json", JSONPath: "build"}, "42"},
+		{"json path and regex", &registry4.Checkver{URL: server.URL + "/api.json", JSONPath: "data.0.version", Regex: `^(\d+\.\d+)`}, "3.1"},
+		{"github", &registry4.Checkver{GitHub: "example/example"}, "1.2.3"},
+		{"github with regex", &registry4.Checkver{GitHub: "example/example", Regex: `v(\d+)`}, "1"},
+	}
+
+	for _, test := range tests {
+		got, err := Latest(test.checkver)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLatestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		checkver *registry4.Checkver
+	}{
+		{"empty", &registry4.Checkver{}},
+		{"url without regex or json path", &registry4.Checkver{URL: server.URL}},
+		{"not found", &registry4.Checkver{URL: server.URL + "/missing", Regex: "."}},
+		{"no match", &registry4.Checkver{URL: server.URL, Regex: `\d+\.\d+`}},
+		{"index out of range", &registry4.Checkver{URL: server.URL, JSONPath: "data.0"}},
+		{"invalid repository", &registry4.Checkver{GitHub: "example"}},
+	}
+
+	for _, test := range tests {
+		if got, err := Latest(test.checkver); err == nil {
+			t.Errorf("%v: got %v, want an error", test.name, got)
+		}
+	}
+}
@@ -17,19 +17,113 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"os"
 	"os/exec"
 	"strings"
-	"syscall"
+	"time"
 )
 
-// Run runs a command, printing the command line to standard output. Additional output is printed in
-// case we run msiexec and it returns with code 3010 (short for "reboot needed").
+// Options are optional settings for running a command.
+type Options struct {
+	// OutputFile, if set, receives the standard output and standard error of the command instead of
+	// the console.
+	OutputFile string
+
+	// LogFile is the log the command has been told to write on its own, if any. It is only used to
+	// describe failures and to detect activity.
+	LogFile string
+
+	// Timeout, if not zero, is how long the command may run before it is killed, along with every
+	// process it started.
+	Timeout time.Duration
+
+	// IdleTimeout, if not zero, is how long the command may go without writing to its output or log
+	// before a warning is logged, since it might be waiting for input nobody will ever give.
+	IdleTimeout time.Duration
+
+	// CommandLine, if set, is the command line given to the command on Windows instead of the quoted
+	// arguments, for programs that parse their command line in their own way. The arguments still
+	// tell which program to run, and are what is given to it on other platforms.
+	CommandLine string
+}
+
+// commandLine returns the command line to show for the given arguments.
+func (o *Options) commandLine(args []string) string {
+	if o != nil && o.CommandLine != "" {
+		return o.CommandLine
+	}
+
+	return CommandLine(args)
+}
+
+// Error is returned when a command fails. It points to the logs of the command, if any, and shows
+// the last lines of the most useful one.
+type Error struct {
+	Args        []string
+	Err         error
+	ExitCode    int    // -1 if the command did not exit normally
+	Description string // Meaning of the exit code, if known, filled in by callers
+	LogFile     string // Empty if the command did not write a log
+	OutputFile  string // Empty if the output of the command was not captured
+	Tail        []string
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%v failed: %v", e.Args[0], e.Err)
+
+	if e.Description != "" {
+		fmt.Fprintf(&b, " (%v)", e.Description)
+	}
+
+	if e.LogFile != "" {
+		fmt.Fprintf(&b, "\nlog: %v", e.LogFile)
+	}
+
+	if e.OutputFile != "" {
+		fmt.Fprintf(&b, "\noutput: %v", e.OutputFile)
+	}
+
+	if len(e.Tail) > 0 {
+		fmt.Fprintf(&b, "\n\n    %v", strings.Join(e.Tail, "\n    "))
+	}
+
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is the Err of the *Error returned when a command is killed for running too long.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("killed after running for %v", e.Timeout)
+}
+
+// Run runs a command, printing the command line to standard output. Any exit code other than 0 is
+// a failure, callers that know better can inspect the ExitCode of the returned *Error.
 func Run(args ...string) error {
+	return RunWithOptions(nil, args...)
+}
+
+// RunWithOptions is the same as Run but allows capturing the output of the command and limiting how
+// long it runs. Failures are reported as an *Error.
+func RunWithOptions(options *Options, args ...string) error {
 	if len(args) < 1 {
 		return errors.New("empty command line")
 	}
 
+	if options == nil {
+		options = &Options{}
+	}
+
 	var cmd *exec.Cmd
 	if len(args) == 1 {
 		cmd = exec.Command(args[0])
@@ -37,32 +131,118 @@ func Run(args ...string) error {
 		cmd = exec.Command(args[0], args[1:]...)
 	}
 
-	log.Println("running", strings.Join(args, " "))
+	log.Println("running", options.commandLine(args))
+
+	if options.OutputFile != "" {
+		output, err := os.Create(options.OutputFile)
+		if err != nil {
+			return fmt.Errorf("could not create output file: %w", err)
+		}
+		defer output.Close()
+
+		cmd.Stdout = output
+		cmd.Stderr = output
+	}
+
+	prepareProcessTree(cmd)
+
+	if options.CommandLine != "" {
+		setCommandLine(cmd, options.CommandLine)
+	}
 
 	err := cmd.Start()
 	if err != nil {
 		return err
 	}
 
-	if err := cmd.Wait(); err != nil {
-		exiterr, ok := err.(*exec.ExitError)
-		if !ok {
-			return err
+	tree, err := newProcessTree(cmd)
+	if err != nil {
+		log.Printf("WARNING: processes started by %v cannot be tracked: %v", args[0], err)
+	}
+	defer tree.close()
+
+	if err := tree.resume(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+
+		return fmt.Errorf("could not start %v: %w", args[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var timeout <-chan time.Time
+	if options.Timeout > 0 {
+		timer := time.NewTimer(options.Timeout)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	var watchdogTick <-chan time.Time
+	watchdog := newWatchdog(options.IdleTimeout, options.OutputFile, options.LogFile)
+	if options.IdleTimeout > 0 {
+		ticker := time.NewTicker(watchdogInterval(options.IdleTimeout))
+		defer ticker.Stop()
+
+		watchdogTick = ticker.C
+	}
+
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				return nil
+			}
+
+			exiterr, ok := err.(*exec.ExitError)
+			if !ok {
+				return err
+			}
+
+			return newError(args, exiterr, exiterr.ExitCode(), options)
+		case <-timeout:
+			log.Printf("%v is still running after %v, killing it", args[0], options.Timeout)
+			if err := tree.kill(); err != nil {
+				log.Printf("WARNING: could not kill all processes started by %v: %v", args[0], err)
+				cmd.Process.Kill()
+			}
+
+			<-done
+
+			return newError(args, &TimeoutError{options.Timeout}, -1, options)
+		case now := <-watchdogTick:
+			if idle, ok := watchdog.check(now); ok {
+				log.Printf("WARNING: %v has shown no activity for %v, it might be waiting for input in a hidden window", args[0], idle.Round(time.Second))
+			}
 		}
+	}
+}
 
-		status, ok := exiterr.Sys().(syscall.WaitStatus)
-		if !ok {
-			return err
+// newError builds the error describing a failed command, with the tail of its log or, if it didn't
+// write one, of its output.
+func newError(args []string, err error, exitCode int, options *Options) *Error {
+	ret := &Error{Args: args, Err: err, ExitCode: exitCode}
+
+	for _, path := range []string{options.OutputFile, options.LogFile} {
+		tail, tailErr := Tail(path, TailLines)
+		if tailErr != nil {
+			continue
 		}
 
-		// msiexec returns 3010 if install needs reboot later
-		if strings.Contains(args[0], "msiexec") && status.ExitStatus() == 3010 {
-			log.Printf("msiexec exited with code 3010, a reboot is required to complete installation")
-			return nil
+		if path == options.LogFile {
+			ret.LogFile = path
+		} else {
+			ret.OutputFile = path
 		}
 
-		return err
+		// Prefer the log, which is usually more detailed than the output
+		if len(tail) > 0 {
+			ret.Tail = tail
+		}
 	}
 
-	return nil
+	return ret
 }
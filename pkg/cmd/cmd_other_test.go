@@ -0,0 +1,123 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// These tests use shell scripts as stand-ins for installers.
+
+func TestRunWithOptionsExitCode(t *testing.T) {
+	dir := t.TempDir()
+	options := &Options{OutputFile: filepath.Join(dir, "output.log")}
+
+	err := RunWithOptions(options, "sh", "-c", "echo first; echo last; exit 3")
+
+	var cmdErr *Error
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("got %v, want an *Error", err)
+	}
+
+	if cmdErr.ExitCode != 3 {
+		t.Errorf("got exit code %v, want 3", cmdErr.ExitCode)
+	}
+
+	if cmdErr.OutputFile != options.OutputFile || strings.Join(cmdErr.Tail, "|") != "first|last" {
+		t.Errorf("got output %v with tail %q", cmdErr.OutputFile, cmdErr.Tail)
+	}
+}
+
+func TestRunWithOptionsPrefersLogTail(t *testing.T) {
+	dir := t.TempDir()
+	options := &Options{OutputFile: filepath.Join(dir, "output.log"), LogFile: filepath.Join(dir, "install.log")}
+
+	err := RunWithOptions(options, "sh", "-c", "echo output; echo 'from the log' > \"$0\"; exit 1", options.LogFile)
+
+	var cmdErr *Error
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("got %v, want an *Error", err)
+	}
+
+	if cmdErr.LogFile != options.LogFile || strings.Join(cmdErr.Tail, "|") != "from the log" {
+		t.Errorf("got log %v with tail %q", cmdErr.LogFile, cmdErr.Tail)
+	}
+}
+
+func TestRunWithOptionsTimeoutKillsProcessTree(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "child.pid")
+
+	// The child outlives its parent unless the whole tree is killed
+	start := time.Now()
+	err := RunWithOptions(&Options{Timeout: 500 * time.Millisecond}, "sh", "-c", "sleep 30 & echo $! > \"$0\"; wait", pidFile)
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("got %v, want a *TimeoutError", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("took %v to kill the command", elapsed)
+	}
+
+	b, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Killed processes linger as zombies until reaped by init, give it a moment
+	deadline := time.Now().Add(5 * time.Second)
+	for !processGone(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %v is still running", pid)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// processGone returns whether the process with the given pid is dead, or a zombie (which is only
+// known on Linux).
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return true
+	}
+
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+
+	// The state follows the command name, which is in parentheses
+	fields := strings.Fields(string(b[strings.LastIndexByte(string(b), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
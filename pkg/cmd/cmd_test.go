@@ -0,0 +1,134 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func TestTail(t *testing.T) {
+	dir := t.TempDir()
+
+	utf16Log := []byte{0xff, 0xfe}
+	for _, unit := range utf16.Encode([]rune("line 1\r\nlíne 2\r\n\r\nline 3\r\n")) {
+		utf16Log = append(utf16Log, byte(unit), byte(unit>>8))
+	}
+
+	long := strings.Repeat("x", maxTailBytes) + "\ntruncated\nend\n"
+
+	tests := []struct {
+		name     string
+		contents []byte
+		n        int
+		want     []string
+	}{
+		{"empty", nil, 5, nil},
+		{"fewer lines", []byte("a\nb\n"), 5, []string{"a", "b"}},
+		{"more lines", []byte("a\nb\nc\nd\n"), 2, []string{"c", "d"}},
+		{"blank lines", []byte("a\n\n  \nb"), 5, []string{"a", "b"}},
+		{"utf-16", utf16Log, 5, []string{"line 1", "líne 2", "line 3"}},
+		{"long", []byte(long), 5, []string{"truncated", "end"}},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(test.name, " ", "-")+".log")
+		if err := ioutil.WriteFile(path, test.contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Tail(path, test.n)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	if _, err := Tail(filepath.Join(dir, "missing.log"), 5); !os.IsNotExist(err) {
+		t.Errorf("got %v for a missing file", err)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.log")
+	start := time.Now()
+	w := newWatchdog(time.Minute, path, "")
+
+	if _, warn := w.check(start.Add(30 * time.Second)); warn {
+		t.Error("warned before the idle timeout")
+	}
+
+	if _, warn := w.check(start.Add(61 * time.Second)); !warn {
+		t.Error("did not warn after the idle timeout")
+	}
+
+	// The next warning is due after another idle timeout
+	if _, warn := w.check(start.Add(90 * time.Second)); warn {
+		t.Error("warned twice in a row")
+	}
+
+	// Writing to the output is activity
+	if err := ioutil.WriteFile(path, []byte("progress"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if idle, warn := w.check(start.Add(150 * time.Second)); warn || idle != 0 {
+		t.Errorf("got idle %v, warn %v after activity", idle, warn)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		idleTimeout time.Duration
+		want        time.Duration
+	}{
+		{time.Second, 100 * time.Millisecond},
+		{10 * time.Second, time.Second},
+		{5 * time.Minute, 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		if got := watchdogInterval(test.idleTimeout); got != test.want {
+			t.Errorf("watchdogInterval(%v) = %v, want %v", test.idleTimeout, got, test.want)
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := &Error{
+		Args:        []string{"setup.exe", "/S"},
+		Err:         &TimeoutError{time.Hour},
+		ExitCode:    -1,
+		Description: "stuck",
+		LogFile:     `C:\logs\install.log`,
+		Tail:        []string{"a", "b"},
+	}
+
+	want := "setup.exe failed: killed after running for 1h0m0s (stuck)\nlog: C:\\logs\\install.log\n\n    a\n    b"
+	if got := err.Error(); got != want {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import "strings"
+
+// WindowsCommandLine returns the command line Windows programs receive for the given arguments, quoted
+// the way exec quotes them on Windows.
+func WindowsCommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = EscapeArg(arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// EscapeArg quotes an argument so that programs parsing their command line like the C runtime does
+// read it back unchanged. It's the same as syscall.EscapeArg, which only exists on Windows.
+func EscapeArg(s string) string {
+	if s == "" {
+		return `""`
+	}
+
+	if !strings.ContainsAny(s, "\"\\ \t") {
+		return s
+	}
+
+	hasSpace := strings.ContainsAny(s, " \t")
+	if !strings.ContainsAny(s, "\"\\") {
+		return `"` + s + `"`
+	}
+
+	var b strings.Builder
+	if hasSpace {
+		b.WriteByte('"')
+	}
+
+	// Backslashes are only special right before a double quote
+	slashes := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	if hasSpace {
+		b.WriteString(strings.Repeat(`\`, slashes))
+		b.WriteByte('"')
+	}
+
+	return b.String()
+}
@@ -0,0 +1,40 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import "testing"
+
+func TestEscapeArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{``, `""`},
+		{`plain`, `plain`},
+		{`C:\Program Files\App`, `"C:\Program Files\App"`},
+		{`C:\Apps\`, `C:\Apps\`},
+		{`C:\Program Files\`, `"C:\Program Files\\"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`a\"b`, `a\\\"b`},
+		{"tab\there", "\"tab\there\""},
+	}
+
+	for _, test := range tests {
+		if got := EscapeArg(test.arg); got != test.want {
+			t.Errorf("EscapeArg(%q) = %v, want %v", test.arg, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// processTree is the set of processes started by a command, which on POSIX systems is the process
+// group the command leads.
+type processTree struct {
+	pid int
+}
+
+// prepareProcessTree sets up a command, before it's started, so that the processes it starts can be
+// tracked.
+func prepareProcessTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func newProcessTree(cmd *exec.Cmd) (*processTree, error) {
+	return &processTree{cmd.Process.Pid}, nil
+}
+
+// resume lets a command that was started with the tree run. Commands are never started suspended on
+// POSIX systems.
+func (t *processTree) resume() error {
+	return nil
+}
+
+// kill kills every process of the tree.
+func (t *processTree) kill() error {
+	return syscall.Kill(-t.pid, syscall.SIGKILL)
+}
+
+func (t *processTree) close() {}
@@ -0,0 +1,164 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procOpenThread               = kernel32.NewProc("OpenThread")
+	procResumeThread             = kernel32.NewProc("ResumeThread")
+	procTerminateJobObject       = kernel32.NewProc("TerminateJobObject")
+	procThread32First            = kernel32.NewProc("Thread32First")
+	procThread32Next             = kernel32.NewProc("Thread32Next")
+)
+
+// Flags and access rights needed to start a process in a job object, from winbase.h, winnt.h and
+// tlhelp32.h.
+const (
+	createSuspended     = 0x00000004
+	processSetQuota     = 0x0100
+	processTerminate    = 0x0001
+	th32csSnapThread    = 0x00000004
+	threadSuspendResume = 0x0002
+)
+
+// threadEntry32 is THREADENTRY32.
+type threadEntry32 struct {
+	Size           uint32
+	Usage          uint32
+	ThreadID       uint32
+	OwnerProcessID uint32
+	BasePri        int32
+	DeltaPri       int32
+	Flags          uint32
+}
+
+// processTree is the set of processes started by a command, which on Windows is tracked with a job
+// object, since processes don't belong to their parent.
+type processTree struct {
+	job     syscall.Handle
+	process *os.Process // Killed on its own if the job object could not be set up
+}
+
+// prepareProcessTree sets up a command, before it's started, so that the processes it starts can be
+// tracked: it's started suspended, so that it can be put in a job object before it gets a chance to
+// start other processes.
+func prepareProcessTree(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.CreationFlags |= createSuspended
+}
+
+// newProcessTree puts a started command in a new job object, so that the processes it starts are
+// part of the job too. The returned tree can be used even if that fails.
+func newProcessTree(cmd *exec.Cmd) (*processTree, error) {
+	ret := &processTree{process: cmd.Process}
+
+	job, _, err := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return ret, err
+	}
+
+	process, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		syscall.CloseHandle(syscall.Handle(job))
+		return ret, err
+	}
+	defer syscall.CloseHandle(process)
+
+	if ok, _, err := procAssignProcessToJobObject.Call(job, uintptr(process)); ok == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return ret, err
+	}
+
+	ret.job = syscall.Handle(job)
+
+	return ret, nil
+}
+
+// resume lets the command run, once it's in the job object. Its only thread is looked up among the
+// threads of the system, since exec doesn't give access to it.
+func (t *processTree) resume() error {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(th32csSnapThread, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	entry := threadEntry32{}
+	entry.Size = uint32(unsafe.Sizeof(entry))
+
+	resumed := false
+	ok, _, err := procThread32First.Call(uintptr(snapshot), uintptr(unsafe.Pointer(&entry)))
+	for ; ok != 0; ok, _, err = procThread32Next.Call(uintptr(snapshot), uintptr(unsafe.Pointer(&entry))) {
+		if entry.OwnerProcessID != uint32(t.process.Pid) {
+			continue
+		}
+
+		thread, _, err := procOpenThread.Call(threadSuspendResume, 0, uintptr(entry.ThreadID))
+		if thread == 0 {
+			return err
+		}
+
+		count, _, err := procResumeThread.Call(thread)
+		syscall.CloseHandle(syscall.Handle(thread))
+		if count == 0xffffffff {
+			return err
+		}
+
+		resumed = true
+	}
+
+	if err != syscall.ERROR_NO_MORE_FILES {
+		return err
+	}
+
+	if !resumed {
+		return errors.New("could not find the main thread of the process")
+	}
+
+	return nil
+}
+
+// kill kills every process of the tree.
+func (t *processTree) kill() error {
+	if t.job == 0 {
+		return t.process.Kill()
+	}
+
+	if ok, _, err := procTerminateJobObject.Call(uintptr(t.job), 1); ok == 0 {
+		return err
+	}
+
+	return nil
+}
+
+func (t *processTree) close() {
+	if t.job != 0 {
+		syscall.CloseHandle(t.job)
+	}
+}
@@ -0,0 +1,68 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"log"
+	"strings"
+)
+
+// Runner runs commands. It allows replacing the actual execution of commands, such as installers,
+// with something else.
+type Runner interface {
+	Run(options *Options, args ...string) error
+}
+
+// ExecRunner runs commands with RunWithOptions.
+type ExecRunner struct{}
+
+// Run implements Runner.
+func (ExecRunner) Run(options *Options, args ...string) error {
+	return RunWithOptions(options, args...)
+}
+
+// DryRunner only logs the commands it is given, as if they succeeded.
+type DryRunner struct{}
+
+// Run implements Runner.
+func (DryRunner) Run(options *Options, args ...string) error {
+	log.Println("would run", options.commandLine(args))
+
+	if options != nil && options.Timeout > 0 {
+		log.Println("    killing it after", options.Timeout)
+	}
+
+	if options != nil && options.OutputFile != "" {
+		log.Println("    with its output in", options.OutputFile)
+	}
+
+	return nil
+}
+
+// CommandLine returns the given command as it would be typed in a shell, quoting arguments with
+// spaces.
+func CommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+
+		quoted[i] = arg
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,107 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// TailLines is the number of lines of logs shown when a command fails.
+const TailLines = 20
+
+// maxTailBytes is how much of the end of a log is read to find its last lines.
+const maxTailBytes = 64 * 1024
+
+// Tail returns the last n non-empty lines of the given log file. Logs written in UTF-16, as some
+// installers do, are decoded.
+func Tail(path string, n int) ([]string, error) {
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// The byte order mark, if any, is at the beginning of the file
+	bom := make([]byte, 2)
+	utf16le := false
+	if _, err := f.ReadAt(bom, 0); err == nil {
+		utf16le = bytes.Equal(bom, []byte{0xff, 0xfe})
+	}
+
+	offset := info.Size() - maxTailBytes
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Stay aligned to code units
+	if utf16le && offset%2 != 0 {
+		offset++
+	}
+
+	buf := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+
+	var text string
+	if utf16le {
+		text = decodeUTF16LE(buf)
+	} else {
+		text = string(buf)
+	}
+
+	var ret []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		line = strings.TrimPrefix(line, "\ufeff")
+
+		if strings.TrimSpace(line) != "" {
+			ret = append(ret, line)
+		}
+	}
+
+	// The first line is likely to be truncated
+	if offset > 0 && len(ret) > 0 {
+		ret = ret[1:]
+	}
+
+	if len(ret) > n {
+		ret = ret[len(ret)-n:]
+	}
+
+	return ret, nil
+}
+
+func decodeUTF16LE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+	}
+
+	return string(utf16.Decode(units))
+}
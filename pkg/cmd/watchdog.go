@@ -0,0 +1,85 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"time"
+)
+
+// watchdog tells when a command has shown no activity for a while. Activity is any change to the
+// files the command writes its output or log to.
+type watchdog struct {
+	idleTimeout  time.Duration
+	files        map[string]os.FileInfo // Last seen state of each watched file, nil if missing
+	lastActivity time.Time
+}
+
+func newWatchdog(idleTimeout time.Duration, files ...string) *watchdog {
+	ret := &watchdog{
+		idleTimeout:  idleTimeout,
+		files:        make(map[string]os.FileInfo),
+		lastActivity: time.Now(),
+	}
+
+	for _, path := range files {
+		if path != "" {
+			ret.files[path] = nil
+		}
+	}
+
+	return ret
+}
+
+// check looks for activity, returning how long the command has been idle and whether that is long
+// enough to warn about. Once a warning is due, the next one is due after another idle timeout.
+func (w *watchdog) check(now time.Time) (time.Duration, bool) {
+	for path, previous := range w.files {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		if previous == nil || info.Size() != previous.Size() || !info.ModTime().Equal(previous.ModTime()) {
+			w.files[path] = info
+			w.lastActivity = now
+		}
+	}
+
+	idle := now.Sub(w.lastActivity)
+	if idle < w.idleTimeout {
+		return idle, false
+	}
+
+	w.lastActivity = now
+
+	return idle, true
+}
+
+// watchdogInterval returns how often activity is checked for the given idle timeout.
+func watchdogInterval(idleTimeout time.Duration) time.Duration {
+	ret := idleTimeout / 10
+
+	if ret < 100*time.Millisecond {
+		return 100 * time.Millisecond
+	}
+
+	if ret > 10*time.Second {
+		return 10 * time.Second
+	}
+
+	return ret
+}
@@ -0,0 +1,281 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dataformat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a supported data format.
+type Format string
+
+// Supported data formats.
+const (
+	JSON Format = "json"
+	TOML Format = "toml"
+	YAML Format = "yaml"
+)
+
+// IsValid returns whether the given format is known.
+func (f Format) IsValid() bool {
+	switch f {
+	case JSON, TOML, YAML:
+		return true
+	default:
+		return false
+	}
+}
+
+// Extension returns the canonical file extension of the given format, including the leading dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// FromExtension returns the format associated with the extension of the given path, if any.
+func FromExtension(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON, true
+	case ".toml":
+		return TOML, true
+	case ".yaml", ".yml":
+		return YAML, true
+	default:
+		return "", false
+	}
+}
+
+// tomlKeyValueRegexp matches the first line of a TOML document starting with a key/value pair.
+var tomlKeyValueRegexp = regexp.MustCompile(`^("[^"]*"|[A-Za-z0-9_$-]+)\s*=`)
+
+// Sniff guesses the format of the given document from its contents. Documents that look like
+// neither JSON nor TOML are assumed to be YAML.
+func Sniff(b []byte) Format {
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "{"):
+			return JSON
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			return TOML
+		case tomlKeyValueRegexp.MatchString(line):
+			return TOML
+		default:
+			return YAML
+		}
+	}
+
+	return JSON
+}
+
+// Detect returns the format of the document at the given path with the given contents. YAML and
+// TOML extensions are trusted, anything else (including ".json", since downloaded files are always
+// saved with that extension) is sniffed.
+func Detect(path string, b []byte) Format {
+	if format, ok := FromExtension(path); ok && format != JSON {
+		return format
+	}
+
+	return Sniff(b)
+}
+
+// ToJSON converts a document in the given format to JSON.
+func ToJSON(b []byte, format Format) ([]byte, error) {
+	var doc interface{}
+
+	switch format {
+	case JSON:
+		return b, nil
+	case TOML:
+		if _, err := toml.Decode(string(b), &doc); err != nil {
+			return nil, fmt.Errorf("could not decode TOML: %w", err)
+		}
+	case YAML:
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("could not decode YAML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format: %v", format)
+	}
+
+	doc, err := jsonCompatible(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+// FromJSON converts a JSON document to the given format.
+func FromJSON(b []byte, format Format) ([]byte, error) {
+	if format == JSON {
+		return b, nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	switch format {
+	case TOML:
+		if err := toml.NewEncoder(&buf).Encode(tomlCompatible(doc)); err != nil {
+			return nil, fmt.Errorf("could not encode TOML: %w", err)
+		}
+	case YAML:
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+
+		if err := encoder.Encode(doc); err != nil {
+			return nil, fmt.Errorf("could not encode YAML: %w", err)
+		}
+
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format: %v", format)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Marshal encodes the given value in the given format, in a canonical way: in JSON, objects keep the
+// field order of their Go type and maps are sorted by key, while YAML and TOML sort all keys;
+// indentation is two spaces, HTML characters are not escaped (which would make URLs with query
+// strings unreadable) and there is a trailing newline.
+func Marshal(v interface{}, format Format) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return FromJSON(buf.Bytes(), format)
+}
+
+// jsonCompatible converts values produced by the YAML and TOML decoders to values that
+// encoding/json can marshal (i.e. maps with string keys and timestamps as strings).
+func jsonCompatible(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			converted, err := jsonCompatible(item)
+			if err != nil {
+				return nil, err
+			}
+
+			value[k] = converted
+		}
+
+		return value, nil
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(value))
+
+		for k, item := range value {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported non-string key: %v", k)
+			}
+
+			converted, err := jsonCompatible(item)
+			if err != nil {
+				return nil, err
+			}
+
+			ret[key] = converted
+		}
+
+		return ret, nil
+	case []interface{}:
+		for i, item := range value {
+			converted, err := jsonCompatible(item)
+			if err != nil {
+				return nil, err
+			}
+
+			value[i] = converted
+		}
+
+		return value, nil
+	case []map[string]interface{}:
+		ret := make([]interface{}, len(value))
+
+		for i, item := range value {
+			converted, err := jsonCompatible(item)
+			if err != nil {
+				return nil, err
+			}
+
+			ret[i] = converted
+		}
+
+		return ret, nil
+	case time.Time:
+		return value.Format(time.RFC3339), nil
+	default:
+		return value, nil
+	}
+}
+
+// tomlCompatible removes null values from objects, since TOML has no way to represent them, and
+// turns integral numbers back into integers (encoding/json decodes all numbers as float64).
+func tomlCompatible(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			if item == nil {
+				delete(value, k)
+				continue
+			}
+
+			value[k] = tomlCompatible(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = tomlCompatible(item)
+		}
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return int64(value)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,190 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dataformat
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFromExtension(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   Format
+		wantOK bool
+	}{
+		{"registry.json", JSON, true},
+		{"registry.JSON", JSON, true},
+		{"registry.toml", TOML, true},
+		{"registry.yaml", YAML, true},
+		{"dir/registry.yml", YAML, true},
+		{"registry.txt", "", false},
+		{"registry", "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := FromExtension(test.path)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("FromExtension(%q) = %v, %v, want %v, %v", test.path, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		doc  string
+		want Format
+	}{
+		{"JSON", "registry.json", "{\"version\": 5}", JSON},
+		{"JSON with BOM and blank lines", "registry.json", "\xef\xbb\xbf\n\n  {\"version\": 5}", JSON},
+		{"empty", "registry.json", "", JSON},
+		{"TOML key", "registry.json", "# comment\nversion = 5\n", TOML},
+		{"TOML quoted key", "registry.json", "\"$schema\" = \"x\"\n", TOML},
+		{"TOML table", "registry.json", "[packages.example]\nversion = \"1.0\"\n", TOML},
+		{"YAML", "registry.json", "version: 5\npackages: {}\n", YAML},
+		{"YAML list", "registry", "- a\n- b\n", YAML},
+		{"trusted YAML extension", "registry.yaml", "{\"version\": 5}", YAML},
+		{"trusted TOML extension", "registry.toml", "version: 5", TOML},
+	}
+
+	for _, test := range tests {
+		if got := Detect(test.path, []byte(test.doc)); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	const doc = `{
+  "version": 5,
+  "packages": {
+    "example": {
+      "version": "1.2.3",
+      "url": "https://example.com/download?file=setup.exe&arch=x64",
+      "timeout": 600,
+      "ratio": 0.5,
+      "enabled": true,
+      "aliases": ["ex", "sample"],
+      "installers": [{"arch": "x86", "kind": "nsis"}, {"arch": "x86_64", "kind": "msi"}]
+    }
+  }
+}`
+
+	var want interface{}
+	if err := json.Unmarshal([]byte(doc), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []Format{JSON, TOML, YAML} {
+		converted, err := FromJSON([]byte(doc), format)
+		if err != nil {
+			t.Errorf("%v: %v", format, err)
+			continue
+		}
+
+		if got := Sniff(converted); got != format {
+			t.Errorf("%v: converted document sniffed as %v", format, got)
+		}
+
+		back, err := ToJSON(converted, format)
+		if err != nil {
+			t.Errorf("%v: %v", format, err)
+			continue
+		}
+
+		var got interface{}
+		if err := json.Unmarshal(back, &got); err != nil {
+			t.Errorf("%v: %v", format, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", format, got, want)
+		}
+	}
+}
+
+func TestTOMLDropsNulls(t *testing.T) {
+	converted, err := FromJSON([]byte(`{"a": 1, "b": null, "c": {"d": null, "e": "x"}}`), TOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := ToJSON(converted, TOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := string(back), `{"a":1,"c":{"e":"x"}}`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		format  Format
+		want    string
+		wantErr bool
+	}{
+		{"JSON is unchanged", `{ "a" : 1 }`, JSON, `{ "a" : 1 }`, false},
+		{"TOML datetime", "released = 2020-05-17T13:37:42Z\n", TOML, `{"released":"2020-05-17T13:37:42Z"}`, false},
+		{"YAML timestamp", "released: 2020-05-17T13:37:42Z\n", YAML, `{"released":"2020-05-17T13:37:42Z"}`, false},
+		{"YAML nested maps", "a:\n  b:\n    - c: 1\n", YAML, `{"a":{"b":[{"c":1}]}}`, false},
+		{"YAML non-string key", "1: a\n", YAML, "", true},
+		{"invalid TOML", "a = \n", TOML, "", true},
+		{"invalid YAML", "a: [\n", YAML, "", true},
+		{"unsupported format", "a", Format("ini"), "", true},
+	}
+
+	for _, test := range tests {
+		got, err := ToJSON([]byte(test.doc), test.format)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: error = %v, want error %v", test.name, err, test.wantErr)
+		} else if string(got) != test.want {
+			t.Errorf("%v: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	v := struct {
+		Version int               `json:"version"`
+		URL     string            `json:"url"`
+		Tags    map[string]string `json:"tags"`
+	}{5, "https://example.com/?a=1&b=<2>", map[string]string{"z": "last", "a": "first"}}
+
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{JSON, "{\n  \"version\": 5,\n  \"url\": \"https://example.com/?a=1&b=<2>\",\n  \"tags\": {\n    \"a\": \"first\",\n    \"z\": \"last\"\n  }\n}\n"},
+		{YAML, "tags:\n  a: first\n  z: last\nurl: https://example.com/?a=1&b=<2>\nversion: 5\n"},
+	}
+
+	for _, test := range tests {
+		got, err := Marshal(v, test.format)
+		if err != nil {
+			t.Errorf("%v: %v", test.format, err)
+		} else if string(got) != test.want {
+			t.Errorf("%v: got\n%s\nwant\n%s", test.format, got, test.want)
+		}
+	}
+}
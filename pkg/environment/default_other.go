@@ -0,0 +1,41 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !windows
+// +build !windows
+
+package environment
+
+import (
+	"path/filepath"
+
+	"github.com/just-install/just-install/pkg/paths"
+)
+
+// platformDefault keeps the environment in a file below the data directory, since there is no
+// persistent environment to change outside of Windows.
+func platformDefault(user bool) (Writer, error) {
+	dir, err := paths.DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	name := "environment.json"
+	if user {
+		name = "environment-user.json"
+	}
+
+	return &FileWriter{Path: filepath.Join(dir, name)}, nil
+}
@@ -0,0 +1,121 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package environment
+
+import (
+	"os"
+	"strings"
+)
+
+// Writer reads and changes persistent environment variables. Names are case-insensitive, as they
+// are on Windows.
+type Writer interface {
+	// Get returns the value of the given variable, and whether it is set.
+	Get(name string) (string, bool, error)
+	Set(name string, value string) error
+	Delete(name string) error
+}
+
+// PathVariable is the name of the variable listing the directories searched for executables.
+const PathVariable = "PATH"
+
+// pathSeparator separates the directories listed in PathVariable.
+const pathSeparator = ";"
+
+// Default returns the writer of the persistent environment of the machine or, if user is true, of
+// the current user. The JUST_INSTALL_ENVIRONMENT_FILE environment variable can name a file to use
+// instead, for testing.
+func Default(user bool) (Writer, error) {
+	if path := os.Getenv("JUST_INSTALL_ENVIRONMENT_FILE"); path != "" {
+		if user {
+			path += ".user"
+		}
+
+		return &FileWriter{Path: path}, nil
+	}
+
+	return platformDefault(user)
+}
+
+// AddToPath appends the given directory to PATH, unless it's already there, and returns whether it
+// was added.
+func AddToPath(w Writer, dir string) (bool, error) {
+	path, _, err := w.Get(PathVariable)
+	if err != nil {
+		return false, err
+	}
+
+	entries := splitPath(path)
+	if indexOfDir(entries, dir) >= 0 {
+		return false, nil
+	}
+
+	if err := w.Set(PathVariable, strings.Join(append(entries, dir), pathSeparator)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RemoveFromPath removes every occurrence of the given directory from PATH.
+func RemoveFromPath(w Writer, dir string) error {
+	path, ok, err := w.Get(PathVariable)
+	if err != nil || !ok {
+		return err
+	}
+
+	entries := splitPath(path)
+	found := false
+
+	for i := indexOfDir(entries, dir); i >= 0; i = indexOfDir(entries, dir) {
+		entries = append(entries[:i], entries[i+1:]...)
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+
+	return w.Set(PathVariable, strings.Join(entries, pathSeparator))
+}
+
+// splitPath returns the non-empty entries of the given PATH.
+func splitPath(path string) []string {
+	var ret []string
+	for _, entry := range strings.Split(path, pathSeparator) {
+		if strings.TrimSpace(entry) != "" {
+			ret = append(ret, entry)
+		}
+	}
+
+	return ret
+}
+
+// indexOfDir returns the index of the given directory in the given PATH entries, ignoring case and
+// trailing separators, or -1 if it's missing.
+func indexOfDir(entries []string, dir string) int {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.TrimRight(strings.TrimSpace(s), `\/`))
+	}
+
+	for i, entry := range entries {
+		if normalize(entry) == normalize(dir) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,178 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package environment
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileWriter(t *testing.T) {
+	w := &FileWriter{Path: filepath.Join(t.TempDir(), "env", "environment.json")}
+
+	if _, ok, err := w.Get("JAVA_HOME"); ok || err != nil {
+		t.Fatalf("missing file: got %v, %v, want an unset variable", ok, err)
+	}
+
+	steps := []struct {
+		set    string // Variable to set, or to delete if value is empty
+		value  string
+		get    string
+		want   string
+		wantOK bool
+	}{
+		{"JAVA_HOME", "C:\\Java", "JAVA_HOME", "C:\\Java", true},
+		{"java_home", "D:\\Java", "Java_Home", "D:\\Java", true},
+		{"GOPATH", "C:\\Go", "JAVA_HOME", "D:\\Java", true},
+		{"java_home", "", "JAVA_HOME", "", false},
+		{"NOT_SET", "", "GOPATH", "C:\\Go", true},
+	}
+
+	for _, step := range steps {
+		var err error
+		if step.value != "" {
+			err = w.Set(step.set, step.value)
+		} else {
+			err = w.Delete(step.set)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Go through a new writer, so that nothing is cached
+		got, ok, err := (&FileWriter{Path: w.Path}).Get(step.get)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != step.want || ok != step.wantOK {
+			t.Errorf("after changing %v: %v = %q, %v, want %q, %v", step.set, step.get, got, ok, step.want, step.wantOK)
+		}
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Dir(w.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("directory holds %v files, want only the environment file", len(entries))
+	}
+}
+
+func TestFileWriterCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "environment.json")
+	if err := ioutil.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &FileWriter{Path: path}
+	if err := w.Set("A", "1"); err == nil {
+		t.Error("corrupt file was overwritten")
+	}
+
+	if b, _ := ioutil.ReadFile(path); string(b) != "{" {
+		t.Errorf("corrupt file was changed to %q", b)
+	}
+}
+
+func TestAddToPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string // Initial PATH, unset if empty
+		dir       string
+		want      string
+		wantAdded bool
+	}{
+		{"unset", "", "C:\\Tools", "C:\\Tools", true},
+		{"appended", "C:\\Windows;C:\\Windows\\System32", "C:\\Tools", "C:\\Windows;C:\\Windows\\System32;C:\\Tools", true},
+		{"empty entries dropped", "C:\\Windows;;C:\\Other;", "C:\\Tools", "C:\\Windows;C:\\Other;C:\\Tools", true},
+		{"already there", "C:\\Windows;C:\\Tools", "C:\\Tools", "C:\\Windows;C:\\Tools", false},
+		{"different case and trailing separator", "C:\\Windows;c:\\tools\\", "C:\\Tools", "C:\\Windows;c:\\tools\\", false},
+	}
+
+	for _, test := range tests {
+		w := &FileWriter{Path: filepath.Join(t.TempDir(), "environment.json")}
+		if test.path != "" {
+			if err := w.Set(PathVariable, test.path); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		added, err := AddToPath(w, test.dir)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		got, _, _ := w.Get(PathVariable)
+		if got != test.want || added != test.wantAdded {
+			t.Errorf("%v: got %q, added %v, want %q, added %v", test.name, got, added, test.want, test.wantAdded)
+		}
+	}
+}
+
+func TestRemoveFromPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string // Initial PATH, unset if empty
+		dir    string
+		want   string
+		wantOK bool // Whether PATH is set afterwards
+	}{
+		{"unset", "", "C:\\Tools", "", false},
+		{"missing", "C:\\Windows", "C:\\Tools", "C:\\Windows", true},
+		{"removed", "C:\\Windows;C:\\Tools;C:\\Other", "C:\\Tools", "C:\\Windows;C:\\Other", true},
+		{"every occurrence", "C:\\Tools;C:\\Windows;c:\\TOOLS\\", "C:\\Tools", "C:\\Windows", true},
+		{"last entry", "C:\\Tools", "C:\\Tools", "", true},
+	}
+
+	for _, test := range tests {
+		w := &FileWriter{Path: filepath.Join(t.TempDir(), "environment.json")}
+		if test.path != "" {
+			if err := w.Set(PathVariable, test.path); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := RemoveFromPath(w, test.dir); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		got, ok, _ := w.Get(PathVariable)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("%v: got %q, %v, want %q, %v", test.name, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestDefaultEnvironmentFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "environment.json")
+	t.Setenv("JUST_INSTALL_ENVIRONMENT_FILE", path)
+
+	for user, want := range map[bool]string{false: path, true: path + ".user"} {
+		w, err := Default(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f, ok := w.(*FileWriter); !ok || f.Path != want {
+			t.Errorf("Default(%v) = %#v, want a file writer for %v", user, w, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package environment
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/just-install/just-install/internal/atomicfile"
+)
+
+// FileWriter keeps environment variables in a JSON file, which is useful for testing and on systems
+// where the persistent environment can't be changed.
+type FileWriter struct {
+	Path string
+}
+
+// Get implements Writer.
+func (f *FileWriter) Get(name string) (string, bool, error) {
+	vars, err := f.load()
+	if err != nil {
+		return "", false, err
+	}
+
+	value, ok := vars[strings.ToUpper(name)]
+
+	return value, ok, nil
+}
+
+// Set implements Writer.
+func (f *FileWriter) Set(name string, value string) error {
+	vars, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	vars[strings.ToUpper(name)] = value
+
+	return f.save(vars)
+}
+
+// Delete implements Writer.
+func (f *FileWriter) Delete(name string) error {
+	vars, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	delete(vars, strings.ToUpper(name))
+
+	return f.save(vars)
+}
+
+// load reads the variables from the file, a missing file meaning that no variable is set.
+func (f *FileWriter) load() (map[string]string, error) {
+	ret := map[string]string{}
+
+	b, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (f *FileWriter) save(vars map[string]string) error {
+	b, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(f.Path, append(b, '\n'), 0644)
+}
@@ -0,0 +1,176 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package environment
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                = syscall.NewLazyDLL("advapi32.dll")
+	procRegDeleteValueW     = advapi32.NewProc("RegDeleteValueW")
+	procRegSetValueExW      = advapi32.NewProc("RegSetValueExW")
+	procSendMessageTimeoutW = syscall.NewLazyDLL("user32.dll").NewProc("SendMessageTimeoutW")
+)
+
+// Constants from winuser.h, used to tell running programs that the environment changed.
+const (
+	hwndBroadcast      = 0xffff
+	smtoAbortIfHung    = 0x0002
+	wmSettingChange    = 0x001a
+	settingChangeDelay = 5000 // Milliseconds
+)
+
+// RegistryWriter changes the persistent environment stored in the Windows registry, which is
+// inherited by programs started after the change.
+type RegistryWriter struct {
+	Root syscall.Handle // HKEY_LOCAL_MACHINE or HKEY_CURRENT_USER
+	Key  string
+}
+
+func platformDefault(user bool) (Writer, error) {
+	if user {
+		return &RegistryWriter{syscall.HKEY_CURRENT_USER, `Environment`}, nil
+	}
+
+	return &RegistryWriter{syscall.HKEY_LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`}, nil
+}
+
+// Get implements Writer.
+func (r *RegistryWriter) Get(name string) (string, bool, error) {
+	key, err := r.open(syscall.KEY_QUERY_VALUE)
+	if err != nil {
+		return "", false, err
+	}
+	defer syscall.RegCloseKey(key)
+
+	value, _, err := queryString(key, name)
+	if err == syscall.ERROR_FILE_NOT_FOUND {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// Set implements Writer. Values referencing other variables are stored as REG_EXPAND_SZ, and so are
+// values that already were.
+func (r *RegistryWriter) Set(name string, value string) error {
+	key, err := r.open(syscall.KEY_QUERY_VALUE | syscall.KEY_SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer syscall.RegCloseKey(key)
+
+	valueType := uint32(syscall.REG_SZ)
+	if _, previousType, err := queryString(key, name); err == nil && previousType == syscall.REG_EXPAND_SZ {
+		valueType = syscall.REG_EXPAND_SZ
+	} else if strings.Contains(value, "%") {
+		valueType = syscall.REG_EXPAND_SZ
+	}
+
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := syscall.UTF16FromString(value)
+	if err != nil {
+		return err
+	}
+
+	ret, _, _ := procRegSetValueExW.Call(uintptr(key), uintptr(unsafe.Pointer(namePtr)), 0, uintptr(valueType), uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)*2))
+	if ret != 0 {
+		return syscall.Errno(ret)
+	}
+
+	broadcastChange()
+
+	return nil
+}
+
+// Delete implements Writer.
+func (r *RegistryWriter) Delete(name string) error {
+	key, err := r.open(syscall.KEY_SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer syscall.RegCloseKey(key)
+
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	ret, _, _ := procRegDeleteValueW.Call(uintptr(key), uintptr(unsafe.Pointer(namePtr)))
+	if ret != 0 && syscall.Errno(ret) != syscall.ERROR_FILE_NOT_FOUND {
+		return syscall.Errno(ret)
+	}
+
+	broadcastChange()
+
+	return nil
+}
+
+func (r *RegistryWriter) open(access uint32) (syscall.Handle, error) {
+	keyPtr, err := syscall.UTF16PtrFromString(r.Key)
+	if err != nil {
+		return 0, err
+	}
+
+	var ret syscall.Handle
+	if err := syscall.RegOpenKeyEx(r.Root, keyPtr, 0, access, &ret); err != nil {
+		return 0, err
+	}
+
+	return ret, nil
+}
+
+// queryString returns a string value of the given key, along with its type.
+func queryString(key syscall.Handle, name string) (string, uint32, error) {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var valueType, size uint32
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &valueType, nil, &size); err != nil {
+		return "", 0, err
+	}
+
+	if size == 0 {
+		return "", valueType, nil
+	}
+
+	buf := make([]uint16, size/2+1)
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &valueType, (*byte)(unsafe.Pointer(&buf[0])), &size); err != nil {
+		return "", 0, err
+	}
+
+	return syscall.UTF16ToString(buf), valueType, nil
+}
+
+// broadcastChange tells running programs, such as Explorer, to reload the environment, so that
+// programs started from then on see the change.
+func broadcastChange() {
+	param, _ := syscall.UTF16PtrFromString("Environment")
+
+	var result uintptr
+	procSendMessageTimeoutW.Call(hwndBroadcast, wmSettingChange, 0, uintptr(unsafe.Pointer(param)), smtoAbortIfHung, settingChangeDelay, uintptr(unsafe.Pointer(&result)))
+}
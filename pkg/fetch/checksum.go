@@ -0,0 +1,66 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ChecksumError describes a downloaded file whose checksum doesn't match the expected one.
+type ChecksumError struct {
+	Expected string
+	Received string
+	Path     string
+}
+
+func (c *ChecksumError) Error() string {
+	return fmt.Sprintf("expected SHA-256 %v but received %v instead (%v)", c.Expected, c.Received, c.Path)
+}
+
+// SHA256 returns the hex-encoded SHA-256 checksum of the given file.
+func SHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifySHA256 checks that the given file has the given hex-encoded SHA-256 checksum.
+func VerifySHA256(path string, expected string) error {
+	received, err := SHA256(path)
+	if err != nil {
+		return fmt.Errorf("could not compute checksum of %v: %w", path, err)
+	}
+
+	if !strings.EqualFold(received, expected) {
+		return &ChecksumError{expected, received, path}
+	}
+
+	return nil
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/cookiejar"
@@ -252,6 +253,57 @@ func Fetch(resource string, options *Options) (string, error) {
 	return dest, nil
 }
 
+// MaxReadSize is the maximum size of a resource obtained with Read.
+const MaxReadSize = 16 << 20
+
+// Read obtains the given resource, either a local file or something that can be downloaded via
+// HTTP/HTTPS, and returns its contents. It is meant for small documents, such as API responses, and
+// fails for resources larger than MaxReadSize.
+func Read(resource string, options *Options) ([]byte, error) {
+	// Shortcut: resource is a local file
+	if dry.FileExists(resource) {
+		return ioutil.ReadFile(resource)
+	}
+
+	parsedURL, err := url.Parse(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedURL.Scheme == "file" {
+		return ioutil.ReadFile(parsedURL.Path)
+	}
+
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme: %v", parsedURL.Scheme)
+	}
+
+	if options == nil {
+		options = &Options{}
+	}
+
+	resp, err := get(resource, options)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{http.StatusOK, resp.StatusCode, resource}
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxReadSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > MaxReadSize {
+		return nil, fmt.Errorf("resource is larger than %v bytes (%v)", MaxReadSize, resource)
+	}
+
+	return b, nil
+}
+
 // get performs an HTTP GET request using our custom client and options.
 func get(resource string, options *Options) (*http.Response, error) {
 	req, err := http.NewRequest("GET", resource, nil)
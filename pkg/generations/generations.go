@@ -0,0 +1,155 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package generations
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/just-install/just-install/internal/atomicfile"
+)
+
+// timestampFormat is used to name generation files so that they sort chronologically.
+const timestampFormat = "20060102-150405.000000000"
+
+// Store keeps the file at Path along with its last Keep generations. The current file is always a
+// copy of the newest generation, except when it was written by someone else.
+type Store struct {
+	Path string // Path of the current file.
+	Keep int    // Number of generations to keep (at least one).
+}
+
+// Generation is a past version of the file.
+type Generation struct {
+	Path string
+	Time time.Time
+}
+
+// dir returns the directory where generations are stored.
+func (s *Store) dir() string {
+	return s.Path + ".generations"
+}
+
+// List returns the available generations, newest first.
+func (s *Store) List() ([]*Generation, error) {
+	entries, err := ioutil.ReadDir(s.dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var ret []*Generation
+	for _, entry := range entries {
+		name := entry.Name()
+
+		t, err := time.Parse(timestampFormat, strings.TrimSuffix(name, filepath.Ext(name)))
+		if err != nil || entry.IsDir() {
+			continue // Not ours
+		}
+
+		ret = append(ret, &Generation{Path: filepath.Join(s.dir(), name), Time: t})
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Time.After(ret[j].Time) })
+
+	return ret, nil
+}
+
+// Commit makes the given candidate file the current one, recording it as the newest generation. The
+// candidate must reside on the same volume as Path, since it is moved in place with a rename. Older
+// generations exceeding Keep are deleted.
+func (s *Store) Commit(candidate string) error {
+	if err := os.MkdirAll(s.dir(), 0700); err != nil {
+		return err
+	}
+
+	generation := filepath.Join(s.dir(), time.Now().UTC().Format(timestampFormat)+filepath.Ext(s.Path))
+	if err := atomicfile.Copy(candidate, generation, 0644); err != nil {
+		return fmt.Errorf("could not save generation: %w", err)
+	}
+
+	if err := os.Rename(candidate, s.Path); err != nil {
+		return err
+	}
+
+	return s.prune()
+}
+
+// Restore makes the given generation the current file. The file keeps the time of the generation,
+// so that it is as old as it was when it was committed.
+func (s *Store) Restore(generation *Generation) error {
+	if err := atomicfile.Copy(generation.Path, s.Path, 0644); err != nil {
+		return err
+	}
+
+	return os.Chtimes(s.Path, generation.Time, generation.Time)
+}
+
+// Rollback discards the newest generation and restores the one before it, which is returned.
+func (s *Store) Rollback() (*Generation, error) {
+	generations, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(generations) < 2 {
+		return nil, errors.New("there is no previous generation to roll back to")
+	}
+
+	if err := s.Restore(generations[1]); err != nil {
+		return nil, err
+	}
+
+	// Rolled back files were chosen over the newest one, they are as good as freshly committed ones.
+	now := time.Now()
+	if err := os.Chtimes(s.Path, now, now); err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(generations[0].Path); err != nil {
+		return nil, err
+	}
+
+	return generations[1], nil
+}
+
+// prune deletes generations in excess of Keep.
+func (s *Store) prune() error {
+	keep := s.Keep
+	if keep < 1 {
+		keep = 1
+	}
+
+	generations, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	for i := keep; i < len(generations); i++ {
+		if err := os.Remove(generations[i].Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package generations
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// commit commits a candidate file with the given contents.
+func commit(t *testing.T, s *Store, contents string) {
+	t.Helper()
+
+	candidate := s.Path + ".candidate"
+	if err := ioutil.WriteFile(candidate, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Commit(candidate); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(candidate); !os.IsNotExist(err) {
+		t.Errorf("candidate still exists after commit: %v", err)
+	}
+}
+
+// contentsOf returns the contents of the given generations, newest first.
+func contentsOf(t *testing.T, generations []*Generation) []string {
+	t.Helper()
+
+	var ret []string
+	for _, g := range generations {
+		b, err := ioutil.ReadFile(g.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ret = append(ret, string(b))
+	}
+
+	return ret
+}
+
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name        string
+		keep        int
+		commits     []string
+		rollbacks   int
+		wantCurrent string
+		wantKept    []string // Contents of the generations left, newest first
+		wantErr     bool     // Whether the last rollback fails
+	}{
+		{"single commit", 3, []string{"a"}, 0, "a", []string{"a"}, false},
+		{"pruned", 3, []string{"a", "b", "c", "d", "e"}, 0, "e", []string{"e", "d", "c"}, false},
+		{"keep at least one", 0, []string{"a", "b"}, 0, "b", []string{"b"}, false},
+		{"rollback", 3, []string{"a", "b", "c"}, 1, "b", []string{"b", "a"}, false},
+		{"rollback twice", 3, []string{"a", "b", "c"}, 2, "a", []string{"a"}, false},
+		{"rollback past the oldest", 3, []string{"a", "b"}, 2, "a", []string{"a"}, true},
+		{"rollback without generations", 3, nil, 1, "", nil, true},
+	}
+
+	for _, test := range tests {
+		s := &Store{Path: filepath.Join(t.TempDir(), "registry.json"), Keep: test.keep}
+
+		for _, contents := range test.commits {
+			commit(t, s, contents)
+		}
+
+		var err error
+		for i := 0; i < test.rollbacks; i++ {
+			var generation *Generation
+			if generation, err = s.Rollback(); err != nil {
+				break
+			}
+
+			if filepath.Ext(generation.Path) != ".json" {
+				t.Errorf("%v: generation %v doesn't keep the extension", test.name, generation.Path)
+			}
+		}
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: rollback error = %v, want error %v", test.name, err, test.wantErr)
+		}
+
+		if current, _ := ioutil.ReadFile(s.Path); string(current) != test.wantCurrent {
+			t.Errorf("%v: current file is %q, want %q", test.name, current, test.wantCurrent)
+		}
+
+		generations, err := s.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := contentsOf(t, generations); !reflect.DeepEqual(got, test.wantKept) {
+			t.Errorf("%v: generations are %q, want %q", test.name, got, test.wantKept)
+		}
+	}
+}
+
+func TestListIgnoresForeignFiles(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "registry.json"), Keep: 3}
+	commit(t, s, "a")
+
+	// Including what an interrupted atomicfile.Copy leaves behind
+	for _, name := range []string{"notes.txt", "20200517-133742.000000000.json.123456.tmp"} {
+		if err := ioutil.WriteFile(filepath.Join(s.dir(), name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(s.dir(), "20200517-133742.000000001.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	generations, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := contentsOf(t, generations); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("generations are %q, want only the committed one", got)
+	}
+}
+
+func TestRestoreKeepsTime(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "registry.json"), Keep: 3}
+	commit(t, s, "a")
+	commit(t, s, "b")
+
+	generations, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restoring a generation, as done automatically when a download fails, keeps the file as old as
+	// the generation, while an explicit rollback makes it as good as a fresh one
+	if err := s.Restore(generations[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.ModTime().Equal(generations[1].Time) {
+		t.Errorf("restored file is dated %v, want %v", info.ModTime(), generations[1].Time)
+	}
+
+	before := time.Now()
+	if _, err := s.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err = os.Stat(s.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	if info.ModTime().Before(before.Add(-time.Second)) {
+		t.Errorf("rolled back file is dated %v, want now", info.ModTime())
+	}
+}
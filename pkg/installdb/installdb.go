@@ -0,0 +1,217 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/just-install/just-install/internal/atomicfile"
+	"github.com/just-install/just-install/pkg/paths"
+)
+
+// FormatVersion is the version of the database format understood by this package.
+const FormatVersion = 1
+
+// FileName is the name of the database file inside the data directory.
+const FileName = "installed.json"
+
+// lockTimeout is how long to wait for another process to release the database, and also how old a
+// lock must be to be considered stale (i.e. left behind by a crashed process).
+const lockTimeout = 30 * time.Second
+
+// Database lists the installed packages.
+type Database struct {
+	Version  int                `json:"version"`
+	Packages map[string]*Record `json:"packages"`
+}
+
+// Record describes an installed package.
+type Record struct {
+	Name            string               `json:"name"`
+	Version         string               `json:"version"`
+	Arch            string               `json:"arch"`
+	Language        string               `json:"language,omitempty"`
+	Scope           string               `json:"scope,omitempty"` // Empty if the installer's default was used
+	Kind            string               `json:"kind"`
+	Destination     string               `json:"destination,omitempty"`
+	Shims           []string             `json:"shims,omitempty"`
+	Shortcuts       []string             `json:"shortcuts,omitempty"`
+	Path            []string             `json:"path,omitempty"` // Directories added to PATH by just-install
+	Environment     []*EnvironmentChange `json:"environment,omitempty"`
+	ProductCode     string               `json:"productCode,omitempty"` // MSI product code, for uninstalling
+	Uninstall       []string             `json:"uninstall,omitempty"`   // Uninstaller command line, unexpanded
+	InstallerSHA256 string               `json:"installerSHA256"`
+	InstalledAt     time.Time            `json:"installedAt"`
+	Held            bool                 `json:"held,omitempty"` // Held packages are not upgraded
+}
+
+// EnvironmentChange records an environment variable set by just-install, so that it can be
+// restored when the package is uninstalled.
+type EnvironmentChange struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Previous    string `json:"previous,omitempty"`
+	HadPrevious bool   `json:"hadPrevious,omitempty"` // Whether the variable was set before
+}
+
+// SortedNames returns the names of the installed packages, sorted alphabetically.
+func (d *Database) SortedNames() []string {
+	ret := make([]string, 0, len(d.Packages))
+	for name := range d.Packages {
+		ret = append(ret, name)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// Lookup returns the record of the given installed package, ignoring case.
+func (d *Database) Lookup(name string) (*Record, bool) {
+	if ret, ok := d.Packages[name]; ok {
+		return ret, true
+	}
+
+	for k, v := range d.Packages {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+// Store is the on-disk database. Updates are transactional: they are serialized with a lock file
+// and the database is replaced atomically, so that it is never left half-written.
+type Store struct {
+	Path string
+}
+
+// DefaultStore returns the store in the per-machine data directory.
+func DefaultStore() (*Store, error) {
+	dataDir, err := paths.DataDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not create data directory: %w", err)
+	}
+
+	return &Store{Path: filepath.Join(dataDir, FileName)}, nil
+}
+
+// Load reads the database. A missing database is the same as an empty one.
+func (s *Store) Load() (*Database, error) {
+	b, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return &Database{Version: FormatVersion, Packages: map[string]*Record{}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var ret Database
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return nil, fmt.Errorf("could not decode %v: %w", s.Path, err)
+	}
+
+	if ret.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported installed-package database version %v (%v)", ret.Version, s.Path)
+	}
+
+	if ret.Packages == nil {
+		ret.Packages = map[string]*Record{}
+	}
+
+	return &ret, nil
+}
+
+// Update loads the database, applies the given function to it and, if that succeeds, writes it
+// back. No other process can update the database in the meantime.
+func (s *Store) Update(fn func(*Database) error) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(db); err != nil {
+		return err
+	}
+
+	return s.write(db)
+}
+
+// Remove deletes the record of an installed package.
+func (s *Store) Remove(name string) error {
+	return s.Update(func(db *Database) error {
+		if _, ok := db.Packages[name]; !ok {
+			return fmt.Errorf("package %v is not installed", name)
+		}
+
+		delete(db.Packages, name)
+		return nil
+	})
+}
+
+// write replaces the database atomically.
+func (s *Store) write(db *Database) error {
+	b, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(s.Path, append(b, '\n'), 0644)
+}
+
+// lock acquires the database lock, returning a function that releases it.
+func (s *Store) lock() (func(), error) {
+	lockPath := s.Path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintln(f, os.Getpid())
+			f.Close()
+
+			return func() { os.Remove(lockPath) }, nil
+		} else if !os.IsExist(err) {
+			return nil, fmt.Errorf("could not lock %v: %w", s.Path, err)
+		}
+
+		// Break stale locks
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > lockTimeout {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for another just-install process to release " + lockPath)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,214 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installdb
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	return &Store{Path: filepath.Join(t.TempDir(), FileName)}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+
+	db, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(db.Packages) != 0 {
+		t.Fatalf("missing database has %v packages", len(db.Packages))
+	}
+
+	record := &Record{
+		Name:            "Example",
+		Version:         "1.0",
+		Arch:            "x86_64",
+		Kind:            "zip",
+		Destination:     `C:\Program Files\Example`,
+		Shims:           []string{`C:\Shims\example.exe`},
+		Environment:     []*EnvironmentChange{{Name: "EXAMPLE_HOME", Value: `C:\Example`, Previous: `C:\Old`, HadPrevious: true}},
+		InstallerSHA256: "abcd",
+		InstalledAt:     time.Date(2020, 5, 17, 13, 37, 42, 0, time.UTC),
+		Held:            true,
+	}
+
+	if err := s.Update(func(db *Database) error {
+		db.Packages["Example"] = record
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if db, err = s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := db.Lookup("example")
+	if !ok {
+		t.Fatal("record was not saved")
+	}
+
+	if !reflect.DeepEqual(got, record) {
+		t.Errorf("got %+v, want %+v", got, record)
+	}
+
+	if _, err := os.Stat(s.Path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock was not released: %v", err)
+	}
+
+	if err := s.Remove("Example"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Remove("Example"); err == nil {
+		t.Error("removing a package that is not installed succeeded")
+	}
+}
+
+func TestUpdateFailure(t *testing.T) {
+	s := newTestStore(t)
+
+	if err := s.Update(func(db *Database) error {
+		db.Packages["a"] = &Record{Name: "a"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := errors.New("failure")
+	err := s.Update(func(db *Database) error {
+		db.Packages["b"] = &Record{Name: "b"}
+		return want
+	})
+	if err != want {
+		t.Errorf("got %v, want %v", err, want)
+	}
+
+	db, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := db.SortedNames(); !reflect.DeepEqual(names, []string{"a"}) {
+		t.Errorf("failed update was written: %v", names)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	s := newTestStore(t)
+
+	const updates = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			errs <- s.Update(func(db *Database) error {
+				name := fmt.Sprintf("package-%02d", i)
+				db.Packages[name] = &Record{Name: name}
+				return nil
+			})
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(db.Packages) != updates {
+		t.Errorf("got %v packages, want %v: updates were lost", len(db.Packages), updates)
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	s := newTestStore(t)
+
+	lockPath := s.Path + ".lock"
+	if err := ioutil.WriteFile(lockPath, []byte("12345\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-2 * lockTimeout)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := s.Update(func(db *Database) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > lockTimeout/2 {
+		t.Errorf("stale lock was broken after %v", elapsed)
+	}
+
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("lock was not released: %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"corrupt", "{"},
+		{"unsupported version", `{"version": 2, "packages": {}}`},
+	}
+
+	for _, test := range tests {
+		s := newTestStore(t)
+		if err := ioutil.WriteFile(s.Path, []byte(test.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.Load(); err == nil {
+			t.Errorf("%v: database was loaded", test.name)
+		}
+
+		if err := s.Update(func(db *Database) error { return nil }); err == nil {
+			t.Errorf("%v: database was updated", test.name)
+		}
+
+		if b, _ := ioutil.ReadFile(s.Path); string(b) != test.contents {
+			t.Errorf("%v: database was overwritten", test.name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ContainerKind is a recognized archive format, used both for containers wrapping an installer and
+// for packages that are installed by extracting an archive.
+type ContainerKind string
+
+// Supported container kinds.
+const (
+	Container7Z     ContainerKind = "7z"
+	ContainerCAB    ContainerKind = "cab"
+	ContainerTar    ContainerKind = "tar"
+	ContainerTarBz2 ContainerKind = "tar.bz2"
+	ContainerTarGz  ContainerKind = "tar.gz"
+	ContainerTarXz  ContainerKind = "tar.xz"
+	ContainerTarZst ContainerKind = "tar.zst"
+	ContainerZIP    ContainerKind = "zip"
+)
+
+// ContainerKinds returns the supported container kinds.
+func ContainerKinds() []ContainerKind {
+	return []ContainerKind{Container7Z, ContainerCAB, ContainerTar, ContainerTarBz2, ContainerTarGz, ContainerTarXz, ContainerTarZst, ContainerZIP}
+}
+
+// IsValid returns whether the given container kind is known.
+func (ck ContainerKind) IsValid() bool {
+	for _, kind := range ContainerKinds() {
+		if ck == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Entry is a file or directory stored in a container.
+type Entry struct {
+	Name    string      // Slash-separated path, relative to the root of the container
+	Mode    os.FileMode // Permissions and type (directories have os.ModeDir set)
+	ModTime time.Time
+	Size    int64 // Uncompressed size
+}
+
+// Container is an archive whose entries are read in order.
+type Container interface {
+	// Next advances to the next entry and returns it, along with a reader for its contents (nil for
+	// anything but regular files). The reader is only valid until the following call to Next. At
+	// the end of the container, Next returns io.EOF.
+	Next() (*Entry, io.Reader, error)
+
+	// Close releases the resources held by the container.
+	Close() error
+}
+
+// Container magic numbers, checked in order.
+var containerMagics = []struct {
+	offset int
+	magic  []byte
+	kind   ContainerKind
+}{
+	{0, zipMagic, ContainerZIP},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), Container7Z},
+	{0, []byte("MSCF\x00\x00\x00\x00"), ContainerCAB},
+	{0, []byte{0x1f, 0x8b}, ContainerTarGz},
+	{0, []byte("\xfd7zXZ\x00"), ContainerTarXz},
+	{0, []byte("BZh"), ContainerTarBz2},
+	{0, []byte{0x28, 0xb5, 0x2f, 0xfd}, ContainerTarZst},
+	{257, []byte("ustar"), ContainerTar},
+}
+
+// DetectContainer returns the kind of the container at the given path, judging from its magic
+// number. Compressed streams are assumed to contain a tar archive.
+func DetectContainer(path string) (ContainerKind, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	header = header[:n]
+
+	for _, m := range containerMagics {
+		if len(header) >= m.offset+len(m.magic) && bytes.Equal(header[m.offset:m.offset+len(m.magic)], m.magic) {
+			return m.kind, nil
+		}
+	}
+
+	return "", fmt.Errorf("%v is not a known archive format", filepath.Base(path))
+}
+
+// OpenContainer opens the container of the given kind at the given path. If kind is empty, it is
+// detected from the contents of the file.
+func OpenContainer(path string, kind ContainerKind) (Container, error) {
+	if kind == "" {
+		detected, err := DetectContainer(path)
+		if err != nil {
+			return nil, err
+		}
+
+		kind = detected
+	}
+
+	switch kind {
+	case Container7Z:
+		return open7Z(path)
+	case ContainerCAB:
+		return openCAB(path)
+	case ContainerTar, ContainerTarBz2, ContainerTarGz, ContainerTarXz, ContainerTarZst:
+		return openTar(path, kind)
+	case ContainerZIP:
+		return openZIP(path)
+	default:
+		return nil, fmt.Errorf("unknown container kind: %v", kind)
+	}
+}
@@ -0,0 +1,89 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"io"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// sevenZipContainer reads 7-Zip archives.
+type sevenZipContainer struct {
+	r       *sevenzip.ReadCloser
+	next    int
+	current io.ReadCloser
+}
+
+func open7Z(path string) (Container, error) {
+	r, err := sevenzip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sevenZipContainer{r: r}, nil
+}
+
+func (s *sevenZipContainer) Next() (*Entry, io.Reader, error) {
+	if err := s.closeCurrent(); err != nil {
+		return nil, nil, err
+	}
+
+	if s.next >= len(s.r.File) {
+		return nil, nil, io.EOF
+	}
+
+	f := s.r.File[s.next]
+	s.next++
+
+	entry := &Entry{
+		Name:    strings.ReplaceAll(f.Name, "\\", "/"),
+		Mode:    f.Mode(),
+		ModTime: f.Modified,
+		Size:    int64(f.UncompressedSize),
+	}
+
+	if !entry.Mode.IsRegular() {
+		return entry, nil, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.current = rc
+
+	return entry, rc, nil
+}
+
+func (s *sevenZipContainer) Close() error {
+	s.closeCurrent()
+	return s.r.Close()
+}
+
+// closeCurrent closes the reader of the current entry, if any.
+func (s *sevenZipContainer) closeCurrent() error {
+	if s.current == nil {
+		return nil
+	}
+
+	err := s.current.Close()
+	s.current = nil
+
+	return err
+}
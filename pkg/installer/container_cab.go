@@ -0,0 +1,387 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cabinet flags and limits, see [MS-CAB].
+const (
+	cabFlagPrevCabinet    = 0x0001
+	cabFlagNextCabinet    = 0x0002
+	cabFlagReservePresent = 0x0004
+
+	cabCompressionMask   = 0x000f
+	cabCompressionNone   = 0
+	cabCompressionMSZIP  = 1
+	cabCompressionLZX    = 3
+	cabAttributeReadOnly = 0x01
+
+	cabFolderContinued = 0xfffd // And above: files spanning multiple cabinets
+	mszipWindowSize    = 32 * 1024
+)
+
+// cabHeader is the fixed part of CFHEADER.
+type cabHeader struct {
+	Signature    [4]byte
+	Reserved1    uint32
+	Size         uint32
+	Reserved2    uint32
+	FilesOffset  uint32
+	Reserved3    uint32
+	VersionMinor uint8
+	VersionMajor uint8
+	Folders      uint16
+	Files        uint16
+	Flags        uint16
+	SetID        uint16
+	Index        uint16
+}
+
+// cabFolder is a CFFOLDER entry: a compressed stream holding the contents of one or more files.
+type cabFolder struct {
+	DataOffset  uint32
+	DataBlocks  uint16
+	Compression uint16
+}
+
+// cabFileHeader is the fixed part of a CFFILE entry.
+type cabFileHeader struct {
+	Size         uint32
+	FolderOffset uint32
+	Folder       uint16
+	Date         uint16
+	Time         uint16
+	Attributes   uint16
+}
+
+// cabFile is a file stored in a cabinet.
+type cabFile struct {
+	cabFileHeader
+	name string
+}
+
+// cabContainer reads Microsoft Cabinet files. Only uncompressed and MSZIP-compressed folders are
+// supported, and cabinet sets spanning multiple files are not.
+type cabContainer struct {
+	f           *os.File
+	dataReserve int
+	folders     []*cabFolder
+	files       []*cabFile // Sorted by folder and offset, so that each folder is read only once
+	next        int
+
+	folder   int               // Index of the folder being read, -1 if none
+	stream   *cabFolderReader  // Decompressed stream of the current folder
+	position int64             // Position of stream, relative to the start of the folder
+	current  *io.LimitedReader // Contents of the current file
+}
+
+func openCAB(path string) (Container, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &cabContainer{f: f, folder: -1}
+	if err := ret.readDirectory(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("invalid cabinet: %w", err)
+	}
+
+	return ret, nil
+}
+
+// readDirectory reads the list of folders and files.
+func (c *cabContainer) readDirectory() error {
+	r := bufio.NewReader(c.f)
+
+	var header cabHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	if string(header.Signature[:]) != "MSCF" {
+		return errors.New("bad signature")
+	}
+
+	if header.Flags&(cabFlagPrevCabinet|cabFlagNextCabinet) != 0 {
+		return errors.New("cabinets spanning multiple files are not supported")
+	}
+
+	folderReserve := 0
+	if header.Flags&cabFlagReservePresent != 0 {
+		var reserve struct {
+			Header uint16
+			Folder uint8
+			Data   uint8
+		}
+		if err := binary.Read(r, binary.LittleEndian, &reserve); err != nil {
+			return err
+		}
+
+		if _, err := r.Discard(int(reserve.Header)); err != nil {
+			return err
+		}
+
+		folderReserve = int(reserve.Folder)
+		c.dataReserve = int(reserve.Data)
+	}
+
+	for i := 0; i < int(header.Folders); i++ {
+		folder := &cabFolder{}
+		if err := binary.Read(r, binary.LittleEndian, folder); err != nil {
+			return err
+		}
+
+		if _, err := r.Discard(folderReserve); err != nil {
+			return err
+		}
+
+		c.folders = append(c.folders, folder)
+	}
+
+	if _, err := c.f.Seek(int64(header.FilesOffset), io.SeekStart); err != nil {
+		return err
+	}
+	r.Reset(c.f)
+
+	for i := 0; i < int(header.Files); i++ {
+		file := &cabFile{}
+		if err := binary.Read(r, binary.LittleEndian, &file.cabFileHeader); err != nil {
+			return err
+		}
+
+		name, err := r.ReadString(0)
+		if err != nil {
+			return err
+		}
+
+		// Names are either UTF-8 or, in practice, ASCII. Either way, directories are separated by
+		// backslashes.
+		file.name = strings.ReplaceAll(strings.TrimSuffix(name, "\x00"), "\\", "/")
+
+		if file.Folder >= cabFolderContinued {
+			return errors.New("files spanning multiple cabinets are not supported")
+		} else if int(file.Folder) >= len(c.folders) {
+			return fmt.Errorf("file %v refers to non-existing folder %v", file.name, file.Folder)
+		}
+
+		c.files = append(c.files, file)
+	}
+
+	sort.SliceStable(c.files, func(i, j int) bool {
+		if c.files[i].Folder != c.files[j].Folder {
+			return c.files[i].Folder < c.files[j].Folder
+		}
+
+		return c.files[i].FolderOffset < c.files[j].FolderOffset
+	})
+
+	return nil
+}
+
+func (c *cabContainer) Next() (*Entry, io.Reader, error) {
+	// Skip what's left of the previous file (c.position is updated while reading it)
+	if c.current != nil {
+		_, err := io.Copy(ioutil.Discard, c.current)
+		c.current = nil
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if c.next >= len(c.files) {
+		return nil, nil, io.EOF
+	}
+
+	file := c.files[c.next]
+	c.next++
+
+	offset := int64(file.FolderOffset)
+	if int(file.Folder) != c.folder || offset < c.position {
+		if err := c.openFolder(int(file.Folder)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if _, err := io.CopyN(ioutil.Discard, c.stream, offset-c.position); err != nil {
+		return nil, nil, fmt.Errorf("could not seek to %v: %w", file.name, err)
+	}
+	c.position = offset
+
+	mode := os.FileMode(0644)
+	if file.Attributes&cabAttributeReadOnly != 0 {
+		mode = 0444
+	}
+
+	entry := &Entry{
+		Name:    file.name,
+		Mode:    mode,
+		ModTime: dosTime(file.Date, file.Time),
+		Size:    int64(file.Size),
+	}
+
+	c.current = &io.LimitedReader{R: &countingReader{c.stream, &c.position}, N: int64(file.Size)}
+
+	return entry, c.current, nil
+}
+
+func (c *cabContainer) Close() error {
+	return c.f.Close()
+}
+
+// openFolder starts reading the given folder from the beginning.
+func (c *cabContainer) openFolder(index int) error {
+	folder := c.folders[index]
+
+	switch folder.Compression & cabCompressionMask {
+	case cabCompressionNone, cabCompressionMSZIP:
+	case cabCompressionLZX:
+		return errors.New("LZX-compressed cabinets are not supported")
+	default:
+		return fmt.Errorf("unsupported cabinet compression type %v", folder.Compression&cabCompressionMask)
+	}
+
+	if _, err := c.f.Seek(int64(folder.DataOffset), io.SeekStart); err != nil {
+		return err
+	}
+
+	c.folder = index
+	c.position = 0
+	c.stream = &cabFolderReader{
+		r:           bufio.NewReader(c.f),
+		blocks:      int(folder.DataBlocks),
+		reserve:     c.dataReserve,
+		compression: folder.Compression & cabCompressionMask,
+	}
+
+	return nil
+}
+
+// cabFolderReader decompresses the CFDATA blocks of a folder.
+type cabFolderReader struct {
+	r           *bufio.Reader
+	blocks      int // Blocks left to read
+	reserve     int // Size of the per-block reserved area
+	compression uint16
+	history     []byte // Last decompressed bytes, which MSZIP blocks can refer to
+	buf         []byte // Decompressed data not yet returned
+}
+
+func (fr *cabFolderReader) Read(p []byte) (int, error) {
+	for len(fr.buf) == 0 {
+		if fr.blocks == 0 {
+			return 0, io.EOF
+		}
+
+		if err := fr.readBlock(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, fr.buf)
+	fr.buf = fr.buf[n:]
+
+	return n, nil
+}
+
+// readBlock decompresses the next CFDATA block.
+func (fr *cabFolderReader) readBlock() error {
+	var header struct {
+		Checksum         uint32
+		CompressedSize   uint16
+		UncompressedSize uint16
+	}
+	if err := binary.Read(fr.r, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	if _, err := fr.r.Discard(fr.reserve); err != nil {
+		return err
+	}
+
+	data := make([]byte, header.CompressedSize)
+	if _, err := io.ReadFull(fr.r, data); err != nil {
+		return err
+	}
+
+	fr.blocks--
+
+	if fr.compression == cabCompressionNone {
+		fr.buf = data
+		return nil
+	}
+
+	if !bytes.HasPrefix(data, []byte("CK")) {
+		return errors.New("bad MSZIP block signature")
+	}
+
+	out := make([]byte, header.UncompressedSize)
+	inflater := flate.NewReaderDict(bytes.NewReader(data[2:]), fr.history)
+	if _, err := io.ReadFull(inflater, out); err != nil {
+		return fmt.Errorf("could not decompress MSZIP block: %w", err)
+	}
+	inflater.Close()
+
+	fr.history = append(fr.history, out...)
+	if len(fr.history) > mszipWindowSize {
+		fr.history = append([]byte(nil), fr.history[len(fr.history)-mszipWindowSize:]...)
+	}
+
+	fr.buf = out
+
+	return nil
+}
+
+// countingReader keeps track of how many bytes were read from the underlying reader.
+type countingReader struct {
+	r     io.Reader
+	count *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.count += int64(n)
+
+	return n, err
+}
+
+// dosTime converts an MS-DOS date and time to a time.Time, in local time like the original.
+func dosTime(date uint16, t uint16) time.Time {
+	return time.Date(
+		int(date>>9)+1980,
+		time.Month(date>>5&0x0f),
+		int(date&0x1f),
+		int(t>>11),
+		int(t>>5&0x3f),
+		int(t&0x1f)*2,
+		0,
+		time.Local,
+	)
+}
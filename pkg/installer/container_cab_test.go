@@ -0,0 +1,300 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCabFile is a file written by buildCAB.
+type testCabFile struct {
+	name     string
+	folder   int
+	contents string
+	readOnly bool
+}
+
+// buildCAB builds a single cabinet holding the given files, with one folder per compression type
+// given and the given per-block reserved area. Each folder is split into blocks of at most
+// blockSize bytes. Files are listed in reverse order, to check that they're read back in folder
+// order anyway.
+func buildCAB(t *testing.T, compressions []uint16, files []testCabFile, blockSize int, dataReserve int) []byte {
+	t.Helper()
+
+	// Lay out the uncompressed contents of each folder
+	folderData := make([][]byte, len(compressions))
+	offsets := make([]uint32, len(files))
+	for i, f := range files {
+		offsets[i] = uint32(len(folderData[f.folder]))
+		folderData[f.folder] = append(folderData[f.folder], f.contents...)
+	}
+
+	// Compress each folder into CFDATA blocks
+	blocks := make([][]byte, len(compressions))
+	blockCounts := make([]uint16, len(compressions))
+	for i, compression := range compressions {
+		var history []byte
+		data := folderData[i]
+
+		for len(data) > 0 || blockCounts[i] == 0 {
+			n := blockSize
+			if n > len(data) {
+				n = len(data)
+			}
+			chunk := data[:n]
+			data = data[n:]
+
+			payload := chunk
+			if compression == cabCompressionMSZIP {
+				var buf bytes.Buffer
+				buf.WriteString("CK")
+				w, err := flate.NewWriterDict(&buf, flate.BestCompression, history)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write(chunk)
+				w.Close()
+				payload = buf.Bytes()
+
+				history = append(history, chunk...)
+				if len(history) > mszipWindowSize {
+					history = history[len(history)-mszipWindowSize:]
+				}
+			}
+
+			var block bytes.Buffer
+			binary.Write(&block, binary.LittleEndian, uint32(0))
+			binary.Write(&block, binary.LittleEndian, uint16(len(payload)))
+			binary.Write(&block, binary.LittleEndian, uint16(len(chunk)))
+			block.Write(make([]byte, dataReserve))
+			block.Write(payload)
+
+			blocks[i] = append(blocks[i], block.Bytes()...)
+			blockCounts[i]++
+
+			if len(data) == 0 {
+				break
+			}
+		}
+	}
+
+	var fileEntries bytes.Buffer
+	date := uint16((2020-1980)<<9 | 5<<5 | 17)
+	tm := uint16(13<<11 | 37<<5 | 21)
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+
+		attributes := uint16(0)
+		if f.readOnly {
+			attributes = cabAttributeReadOnly
+		}
+
+		binary.Write(&fileEntries, binary.LittleEndian, cabFileHeader{
+			Size:         uint32(len(f.contents)),
+			FolderOffset: offsets[i],
+			Folder:       uint16(f.folder),
+			Date:         date,
+			Time:         tm,
+			Attributes:   attributes,
+		})
+		fileEntries.WriteString(f.name + "\x00")
+	}
+
+	headerSize := 36
+	flags := uint16(0)
+	if dataReserve > 0 {
+		headerSize += 4
+		flags |= cabFlagReservePresent
+	}
+
+	filesOffset := headerSize + 8*len(compressions)
+	dataOffset := filesOffset + fileEntries.Len()
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.LittleEndian, cabHeader{
+		Signature:    [4]byte{'M', 'S', 'C', 'F'},
+		FilesOffset:  uint32(filesOffset),
+		VersionMinor: 3,
+		VersionMajor: 1,
+		Folders:      uint16(len(compressions)),
+		Files:        uint16(len(files)),
+		Flags:        flags,
+	})
+	if dataReserve > 0 {
+		binary.Write(&out, binary.LittleEndian, struct {
+			Header uint16
+			Folder uint8
+			Data   uint8
+		}{0, 0, uint8(dataReserve)})
+	}
+
+	for i, compression := range compressions {
+		binary.Write(&out, binary.LittleEndian, cabFolder{
+			DataOffset:  uint32(dataOffset),
+			DataBlocks:  blockCounts[i],
+			Compression: compression,
+		})
+		dataOffset += len(blocks[i])
+	}
+
+	out.Write(fileEntries.Bytes())
+	for _, b := range blocks {
+		out.Write(b)
+	}
+
+	b := out.Bytes()
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)))
+
+	return b
+}
+
+func writeTestFile(t *testing.T, name string, b []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestCAB(t *testing.T) {
+	repeated := strings.Repeat("just-install ", 5000) // Spans several blocks, referring back to earlier ones
+
+	files := []testCabFile{
+		{name: "readme.txt", folder: 0, contents: "hello"},
+		{name: "bin\\tool.exe", folder: 0, contents: repeated, readOnly: true},
+		{name: "empty.txt", folder: 0, contents: ""},
+		{name: "data\\second.txt", folder: 1, contents: "second folder"},
+	}
+
+	tests := []struct {
+		name         string
+		compressions []uint16
+		blockSize    int
+		dataReserve  int
+	}{
+		{"uncompressed", []uint16{cabCompressionNone, cabCompressionNone}, 32768, 0},
+		{"MSZIP", []uint16{cabCompressionMSZIP, cabCompressionMSZIP}, 32768, 0},
+		{"MSZIP, small blocks", []uint16{cabCompressionMSZIP, cabCompressionNone}, 1000, 0},
+		{"MSZIP, reserved area", []uint16{cabCompressionMSZIP, cabCompressionMSZIP}, 4096, 6},
+	}
+
+	for _, test := range tests {
+		path := writeTestFile(t, "test.cab", buildCAB(t, test.compressions, files, test.blockSize, test.dataReserve))
+
+		if kind, err := DetectContainer(path); err != nil || kind != ContainerCAB {
+			t.Errorf("%v: detected %v, %v", test.name, kind, err)
+		}
+
+		dest := t.TempDir()
+		if err := Extract(path, "", dest, nil); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		for _, f := range files {
+			path := filepath.Join(dest, filepath.FromSlash(strings.ReplaceAll(f.name, "\\", "/")))
+
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Errorf("%v: %v", test.name, err)
+				continue
+			}
+
+			if string(got) != f.contents {
+				t.Errorf("%v: %v has %v bytes, want %v", test.name, f.name, len(got), len(f.contents))
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := time.Date(2020, 5, 17, 13, 37, 42, 0, time.Local)
+			if !info.ModTime().Equal(want) {
+				t.Errorf("%v: %v modified at %v, want %v", test.name, f.name, info.ModTime(), want)
+			}
+		}
+	}
+}
+
+func TestCABEntries(t *testing.T) {
+	files := []testCabFile{
+		{name: "a.txt", folder: 0, contents: "a"},
+		{name: "b.txt", folder: 0, contents: "bb", readOnly: true},
+	}
+
+	container, err := OpenContainer(writeTestFile(t, "test.cab", buildCAB(t, []uint16{cabCompressionMSZIP}, files, 32768, 0)), ContainerCAB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer container.Close()
+
+	// Entries are returned in folder order and can be skipped without reading them
+	for _, want := range []struct {
+		name string
+		mode os.FileMode
+		size int64
+	}{{"a.txt", 0644, 1}, {"b.txt", 0444, 2}} {
+		entry, _, err := container.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.Name != want.name || entry.Mode != want.mode || entry.Size != want.size {
+			t.Errorf("got %+v, want %+v", entry, want)
+		}
+	}
+}
+
+func TestCABErrors(t *testing.T) {
+	valid := buildCAB(t, []uint16{cabCompressionMSZIP}, []testCabFile{{name: "a.txt", contents: "hello"}}, 32768, 0)
+
+	corrupt := func(f func(b []byte)) []byte {
+		b := append([]byte(nil), valid...)
+		f(b)
+		return b
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+		want string
+	}{
+		{"truncated", valid[:20], "invalid cabinet"},
+		{"bad signature", corrupt(func(b []byte) { b[0] = 'X' }), "bad signature"},
+		{"multiple cabinets", corrupt(func(b []byte) { binary.LittleEndian.PutUint16(b[30:], cabFlagNextCabinet) }), "spanning multiple files"},
+		{"LZX", corrupt(func(b []byte) { binary.LittleEndian.PutUint16(b[36+6:], cabCompressionLZX) }), "LZX"},
+		{"bad MSZIP signature", corrupt(func(b []byte) { b[binary.LittleEndian.Uint32(b[36:])+8] = 'X' }), "MSZIP"},
+	}
+
+	for _, test := range tests {
+		err := Extract(writeTestFile(t, "test.cab", test.b), ContainerCAB, t.TempDir(), nil)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: got %v, want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
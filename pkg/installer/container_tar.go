@@ -0,0 +1,113 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"archive/tar"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// tarContainer reads tar archives, optionally compressed.
+type tarContainer struct {
+	f            *os.File
+	decompressor io.Closer // nil if the archive is not compressed or the decompressor needs no cleanup
+	r            *tar.Reader
+}
+
+func openTar(path string, kind ContainerKind) (Container, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &tarContainer{f: f}
+
+	var stream io.Reader
+	switch kind {
+	case ContainerTar:
+		stream = f
+	case ContainerTarBz2:
+		stream = bzip2.NewReader(f)
+	case ContainerTarGz:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		stream = gz
+		ret.decompressor = gz
+	case ContainerTarXz:
+		stream, err = xz.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	case ContainerTarZst:
+		decoder, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		stream = decoder
+		ret.decompressor = decoder.IOReadCloser()
+	default:
+		f.Close()
+		return nil, fmt.Errorf("not a tar container kind: %v", kind)
+	}
+
+	ret.r = tar.NewReader(stream)
+
+	return ret, nil
+}
+
+func (t *tarContainer) Next() (*Entry, io.Reader, error) {
+	header, err := t.r.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info := header.FileInfo()
+
+	entry := &Entry{
+		Name:    header.Name,
+		Mode:    info.Mode(),
+		ModTime: header.ModTime,
+		Size:    header.Size,
+	}
+
+	if !entry.Mode.IsRegular() {
+		return entry, nil, nil
+	}
+
+	return entry, t.r, nil
+}
+
+func (t *tarContainer) Close() error {
+	if t.decompressor != nil {
+		t.decompressor.Close()
+	}
+
+	return t.f.Close()
+}
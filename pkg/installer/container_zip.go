@@ -0,0 +1,87 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"archive/zip"
+	"io"
+)
+
+// zipContainer reads ZIP archives.
+type zipContainer struct {
+	r       *zip.ReadCloser
+	next    int
+	current io.ReadCloser
+}
+
+func openZIP(path string) (Container, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zipContainer{r: r}, nil
+}
+
+func (z *zipContainer) Next() (*Entry, io.Reader, error) {
+	if err := z.closeCurrent(); err != nil {
+		return nil, nil, err
+	}
+
+	if z.next >= len(z.r.File) {
+		return nil, nil, io.EOF
+	}
+
+	f := z.r.File[z.next]
+	z.next++
+
+	entry := &Entry{
+		Name:    f.Name,
+		Mode:    f.Mode(),
+		ModTime: f.Modified,
+		Size:    int64(f.UncompressedSize64),
+	}
+
+	if !entry.Mode.IsRegular() {
+		return entry, nil, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	z.current = rc
+
+	return entry, rc, nil
+}
+
+func (z *zipContainer) Close() error {
+	z.closeCurrent()
+	return z.r.Close()
+}
+
+// closeCurrent closes the reader of the current entry, if any.
+func (z *zipContainer) closeCurrent() error {
+	if z.current == nil {
+		return nil
+	}
+
+	err := z.current.Close()
+	z.current = nil
+
+	return err
+}
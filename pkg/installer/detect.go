@@ -0,0 +1,269 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/pe"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// Confidence expresses how sure Detect is about its result.
+type Confidence int
+
+// Confidence levels, from lowest to highest.
+const (
+	ConfidenceNone Confidence = iota
+	ConfidenceLow
+	ConfidenceMedium
+	ConfidenceHigh
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceLow:
+		return "low"
+	case ConfidenceMedium:
+		return "medium"
+	case ConfidenceHigh:
+		return "high"
+	default:
+		return "none"
+	}
+}
+
+// Detection is the result of Detect.
+type Detection struct {
+	Type       InstallerType // Probable installer type, empty if the file is not a known installer.
+	Archive    string        // Container kind, for files that are archives rather than installers (e.g. "zip").
+	Confidence Confidence
+	Reason     string // Human readable description of the evidence that was found.
+}
+
+// Magic numbers and signatures.
+var (
+	cfbMagic        = []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1} // MSI databases
+	zipMagic        = []byte("PK\x03\x04")
+	peMagic         = []byte("MZ")
+	nsisMagic       = []byte("\xef\xbe\xad\xdeNullsoftInst")
+	innoMagic       = []byte("Inno Setup Setup Data")
+	innoManifest    = []byte("JR.Inno.Setup")
+	nsisManifest    = []byte("Nullsoft.NSIS")
+	squirrelMarker  = []byte("SquirrelSetup")
+	installShield   = utf16LE("InstallShield")
+	sevenZipSFX     = []byte("7-Zip SFX")
+	sevenZipMagic   = []byte("7z\xbc\xaf\x27\x1c")
+	nupkgMarker     = []byte(".nupkg")
+	advancedMarker  = utf16LE("Advanced Installer")
+	burnSectionName = ".wixburn"
+)
+
+// signature is a byte pattern that, when found in a PE file, hints at a given installer type.
+type signature struct {
+	pattern    []byte
+	typ        InstallerType
+	confidence Confidence
+	reason     string
+}
+
+// peSignatures are searched in the whole PE file, in order of decreasing reliability.
+var peSignatures = []signature{
+	{innoMagic, InnoSetup, ConfidenceHigh, "Inno Setup data header"},
+	{nsisMagic, NSIS, ConfidenceHigh, "NSIS header magic"},
+	{innoManifest, InnoSetup, ConfidenceMedium, "Inno Setup manifest"},
+	{nsisManifest, NSIS, ConfidenceMedium, "NSIS manifest"},
+	{advancedMarker, AdvancedInstaller, ConfidenceMedium, "Advanced Installer version information"},
+	{squirrelMarker, Squirrel, ConfidenceMedium, "Squirrel setup stub"},
+	{installShield, InstallShield, ConfidenceMedium, "InstallShield version information"},
+	{sevenZipSFX, SevenZipSFX, ConfidenceMedium, "7-Zip SFX module"},
+}
+
+// Detect inspects the file at the given path and returns its probable installer type. Files that
+// are not recognized are reported as AsIs with ConfidenceLow, if they are executables, or with an
+// empty type and ConfidenceNone otherwise.
+func Detect(path string) (*Detection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, cfbMagic):
+		confidence := ConfidenceMedium
+		if strings.EqualFold(filepath.Ext(path), ".msi") {
+			confidence = ConfidenceHigh
+		}
+
+		return &Detection{Type: MSI, Confidence: confidence, Reason: "compound file binary (MSI database)"}, nil
+	case bytes.HasPrefix(header, zipMagic):
+		return detectZIP(path)
+	case bytes.HasPrefix(header, peMagic):
+		return detectPE(f)
+	default:
+		if kind, err := DetectContainer(path); err == nil {
+			return &Detection{Archive: string(kind), Confidence: ConfidenceHigh, Reason: string(kind) + " archive"}, nil
+		}
+
+		return &Detection{Confidence: ConfidenceNone, Reason: "unknown file format"}, nil
+	}
+}
+
+// detectZIP tells APPX and MSIX packages apart from plain ZIP archives.
+func detectZIP(path string) (*Detection, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		switch f.Name {
+		case "AppxManifest.xml", "AppxMetadata/AppxBundleManifest.xml":
+			// Both are installed the same way, MSIX packages can only be told apart by their name
+			typ := Appx
+			if ext := strings.ToLower(filepath.Ext(path)); ext == ".msix" || ext == ".msixbundle" {
+				typ = MSIX
+			}
+
+			return &Detection{Type: typ, Confidence: ConfidenceHigh, Reason: "package contains " + f.Name}, nil
+		}
+	}
+
+	return &Detection{Archive: "zip", Confidence: ConfidenceHigh, Reason: "ZIP archive"}, nil
+}
+
+// detectPE looks at section names, the overlay (data appended after the last section) and embedded
+// strings of an executable.
+func detectPE(f *os.File) (*Detection, error) {
+	peFile, err := pe.NewFile(f)
+	if err != nil {
+		return &Detection{Confidence: ConfidenceNone, Reason: fmt.Sprintf("invalid executable: %v", err)}, nil
+	}
+
+	overlayOffset := int64(0)
+	for _, section := range peFile.Sections {
+		if section.Name == burnSectionName {
+			return &Detection{Type: WiXBurn, Confidence: ConfidenceHigh, Reason: "executable has a " + burnSectionName + " section"}, nil
+		}
+
+		if end := int64(section.Offset) + int64(section.Size); end > overlayOffset {
+			overlayOffset = end
+		}
+	}
+
+	// The NSIS first header starts right after the stub, 4 bytes into the overlay.
+	overlay := make([]byte, 4+len(nsisMagic))
+	if n, _ := f.ReadAt(overlay, overlayOffset); n == len(overlay) && bytes.Equal(overlay[4:], nsisMagic) {
+		return &Detection{Type: NSIS, Confidence: ConfidenceHigh, Reason: "overlay starts with the NSIS header"}, nil
+	}
+
+	// 7-Zip SFX modules are followed by a plain 7z archive.
+	if n, _ := f.ReadAt(overlay[:len(sevenZipMagic)], overlayOffset); n == len(sevenZipMagic) && bytes.Equal(overlay[:n], sevenZipMagic) {
+		return &Detection{Type: SevenZipSFX, Confidence: ConfidenceHigh, Reason: "overlay is a 7z archive"}, nil
+	}
+
+	patterns := make([][]byte, len(peSignatures)+1)
+	for i, s := range peSignatures {
+		patterns[i] = s.pattern
+	}
+	patterns[len(peSignatures)] = nupkgMarker
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	found, err := scan(f, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, s := range peSignatures {
+		if !found[i] {
+			continue
+		}
+
+		ret := &Detection{Type: s.typ, Confidence: s.confidence, Reason: s.reason}
+
+		// An embedded NuGet package is what really sets Squirrel installers apart.
+		if s.typ == Squirrel && found[len(peSignatures)] {
+			ret.Confidence = ConfidenceHigh
+			ret.Reason += " with an embedded .nupkg"
+		}
+
+		return ret, nil
+	}
+
+	return &Detection{Type: AsIs, Confidence: ConfidenceLow, Reason: "executable with no known installer signature"}, nil
+}
+
+// scan reads r to the end and returns which of the given patterns it contains.
+func scan(r io.Reader, patterns [][]byte) ([]bool, error) {
+	overlap := 0
+	for _, p := range patterns {
+		if len(p) > overlap {
+			overlap = len(p)
+		}
+	}
+
+	found := make([]bool, len(patterns))
+	buf := make([]byte, 1<<20+overlap)
+	carry := 0
+
+	for {
+		n, err := io.ReadFull(r, buf[carry:])
+		window := buf[:carry+n]
+
+		for i, p := range patterns {
+			if !found[i] && bytes.Contains(window, p) {
+				found[i] = true
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return found, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		// Keep the tail around, so that patterns spanning two reads are found too.
+		carry = copy(buf, window[len(window)-overlap:])
+	}
+}
+
+// utf16LE encodes the given string as UTF-16 (little-endian), as found in PE resources.
+func utf16LE(s string) []byte {
+	var ret []byte
+
+	for _, r := range utf16.Encode([]rune(s)) {
+		ret = append(ret, byte(r), byte(r>>8))
+	}
+
+	return ret
+}
@@ -0,0 +1,175 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// buildPE returns a minimal 32-bit PE file with a single section holding body, followed by the
+// given overlay.
+func buildPE(sectionName string, body []byte, overlay []byte) []byte {
+	const peOffset = 0x40
+	const dataOffset = 0x200
+
+	b := make([]byte, dataOffset)
+	copy(b, peMagic)
+	binary.LittleEndian.PutUint32(b[0x3c:], peOffset)
+
+	h := b[peOffset:]
+	copy(h, "PE\x00\x00")
+	binary.LittleEndian.PutUint16(h[4:], 0x14c) // IMAGE_FILE_MACHINE_I386
+	binary.LittleEndian.PutUint16(h[6:], 1)     // NumberOfSections, no optional header nor symbols
+
+	s := h[24:]
+	copy(s, sectionName)
+	binary.LittleEndian.PutUint32(s[8:], uint32(len(body)))  // VirtualSize
+	binary.LittleEndian.PutUint32(s[12:], 0x1000)            // VirtualAddress
+	binary.LittleEndian.PutUint32(s[16:], uint32(len(body))) // SizeOfRawData
+	binary.LittleEndian.PutUint32(s[20:], dataOffset)        // PointerToRawData
+
+	b = append(b, body...)
+
+	return append(b, overlay...)
+}
+
+// withPadding surrounds the given pattern with filler bytes, as it would be in a real executable.
+func withPadding(pattern []byte) []byte {
+	filler := bytes.Repeat([]byte{0xcc}, 64)
+
+	return append(append(append([]byte{}, filler...), pattern...), filler...)
+}
+
+func TestDetect(t *testing.T) {
+	cfb := append(append([]byte{}, cfbMagic...), make([]byte, 504)...)
+	nsisOverlay := append([]byte{0, 0, 0, 0}, nsisMagic...)
+	squirrel := append(withPadding(squirrelMarker), withPadding([]byte("MyApp-1.0.0-full.nupkg"))...)
+	both := append(withPadding(innoManifest), withPadding(nsisMagic)...)
+	tarHeader := make([]byte, 512)
+	copy(tarHeader[257:], "ustar")
+
+	tests := []struct {
+		name       string
+		file       string
+		contents   []byte
+		typ        InstallerType
+		archive    string
+		confidence Confidence
+	}{
+		{"MSI", "setup.msi", cfb, MSI, "", ConfidenceHigh},
+		{"MSI without extension", "setup.bin", cfb, MSI, "", ConfidenceMedium},
+		{"WiX Burn", "setup.exe", buildPE(burnSectionName, withPadding(nil), nil), WiXBurn, "", ConfidenceHigh},
+		{"NSIS overlay", "setup.exe", buildPE(".text", withPadding(nil), nsisOverlay), NSIS, "", ConfidenceHigh},
+		{"7-Zip SFX archive", "setup.exe", buildPE(".text", withPadding(nil), append(append([]byte{}, sevenZipMagic...), 0, 4)), SevenZipSFX, "", ConfidenceHigh},
+		{"Inno Setup data", "setup.exe", buildPE(".text", withPadding(innoMagic), nil), InnoSetup, "", ConfidenceHigh},
+		{"NSIS header", "setup.exe", buildPE(".text", withPadding(nsisMagic), nil), NSIS, "", ConfidenceHigh},
+		{"Inno Setup manifest", "setup.exe", buildPE(".text", withPadding(innoManifest), nil), InnoSetup, "", ConfidenceMedium},
+		{"NSIS manifest", "setup.exe", buildPE(".text", withPadding(nsisManifest), nil), NSIS, "", ConfidenceMedium},
+		{"Advanced Installer", "setup.exe", buildPE(".rsrc", withPadding(advancedMarker), nil), AdvancedInstaller, "", ConfidenceMedium},
+		{"Squirrel", "setup.exe", buildPE(".text", withPadding(squirrelMarker), nil), Squirrel, "", ConfidenceMedium},
+		{"Squirrel with package", "setup.exe", buildPE(".text", squirrel, nil), Squirrel, "", ConfidenceHigh},
+		{"InstallShield", "setup.exe", buildPE(".rsrc", withPadding(installShield), nil), InstallShield, "", ConfidenceMedium},
+		{"7-Zip SFX module", "setup.exe", buildPE(".text", withPadding(sevenZipSFX), nil), SevenZipSFX, "", ConfidenceMedium},
+		{"most reliable signature wins", "setup.exe", buildPE(".text", both, nil), NSIS, "", ConfidenceHigh},
+		{"signature in the overlay", "setup.exe", buildPE(".text", withPadding(nil), withPadding(innoMagic)), InnoSetup, "", ConfidenceHigh},
+		{"plain executable", "tool.exe", buildPE(".text", withPadding(nil), nil), AsIs, "", ConfidenceLow},
+		{"invalid executable", "tool.exe", []byte("MZ just a text file"), "", "", ConfidenceNone},
+		{"7z archive", "archive.7z", append(append([]byte{}, sevenZipMagic...), 0, 4), "", "7z", ConfidenceHigh},
+		{"tar archive", "archive.tar", tarHeader, "", "tar", ConfidenceHigh},
+		{"unknown", "readme.txt", []byte("Hello, world!"), "", "", ConfidenceNone},
+		{"empty", "empty.bin", nil, "", "", ConfidenceNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Detect(writeTestFile(t, tt.file, tt.contents))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d.Type != tt.typ || d.Archive != tt.archive || d.Confidence != tt.confidence {
+				t.Errorf("Detect() = %q/%q/%v (%v), want %q/%q/%v", d.Type, d.Archive, d.Confidence, d.Reason, tt.typ, tt.archive, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestDetectZIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		entries []testEntry
+		typ     InstallerType
+		archive string
+	}{
+		{"plain archive", "tool.zip", []testEntry{{name: "tool.exe", contents: "MZ"}}, "", "zip"},
+		{"APPX package", "app.appx", []testEntry{{name: "AppxManifest.xml", contents: "<Package/>"}}, Appx, ""},
+		{"APPX bundle", "app.appxbundle", []testEntry{{name: "AppxMetadata/AppxBundleManifest.xml", contents: "<Bundle/>"}}, Appx, ""},
+		{"MSIX package", "app.msix", []testEntry{{name: "AppxManifest.xml", contents: "<Package/>"}}, MSIX, ""},
+		{"MSIX bundle", "app.MSIXBUNDLE", []testEntry{{name: "AppxMetadata/AppxBundleManifest.xml", contents: "<Bundle/>"}}, MSIX, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ioutil.ReadFile(buildZIP(t, tt.entries))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := Detect(writeTestFile(t, tt.file, b))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d.Type != tt.typ || d.Archive != tt.archive || d.Confidence != ConfidenceHigh {
+				t.Errorf("Detect() = %q/%q/%v, want %q/%q/%v", d.Type, d.Archive, d.Confidence, tt.typ, tt.archive, ConfidenceHigh)
+			}
+		})
+	}
+}
+
+func TestScanAcrossReads(t *testing.T) {
+	pattern := []byte("Inno Setup Setup Data")
+
+	// The first read fills a 1 MiB buffer plus room for the longest pattern: place the pattern so
+	// that it straddles the end of it.
+	firstRead := 1<<20 + len(pattern)
+	b := make([]byte, firstRead+100)
+	copy(b[firstRead-len(pattern)/2:], pattern)
+
+	found, err := scan(bytes.NewReader(b), [][]byte{pattern, []byte("missing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !found[0] || found[1] {
+		t.Errorf("scan() = %v, want [true false]", found)
+	}
+}
+
+func TestUTF16LE(t *testing.T) {
+	if got, want := utf16LE("Aé"), []byte{'A', 0, 0xe9, 0}; !bytes.Equal(got, want) {
+		t.Errorf("utf16LE() = %v, want %v", got, want)
+	}
+
+	if !strings.Contains(string(installShield), "I\x00n\x00s\x00") {
+		t.Errorf("installShield = %q, not UTF-16", installShield)
+	}
+}
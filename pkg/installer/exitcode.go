@@ -0,0 +1,111 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+// ExitStatus is the meaning of the exit code of an installer.
+type ExitStatus int
+
+// Possible exit statuses, from best to worst.
+const (
+	ExitSuccess               ExitStatus = iota
+	ExitSuccessRebootRequired            // Installed, but a reboot is needed to complete the installation
+	ExitRetryable                        // Not installed because of a transient condition
+	ExitFailure
+)
+
+func (s ExitStatus) String() string {
+	switch s {
+	case ExitSuccess:
+		return "success"
+	case ExitSuccessRebootRequired:
+		return "success, reboot required"
+	case ExitRetryable:
+		return "retryable"
+	default:
+		return "failure"
+	}
+}
+
+// ExitCode describes an exit code of an installer.
+type ExitCode struct {
+	Status      ExitStatus
+	Description string // Empty if the exit code is not documented
+}
+
+// Exit codes of Windows Installer, shared by installers that wrap it.
+// https://docs.microsoft.com/en-us/windows/win32/msi/error-codes
+var msiExitCodes = map[int]ExitCode{
+	0:    {ExitSuccess, ""},
+	1602: {ExitFailure, "the user cancelled installation"},
+	1603: {ExitFailure, "a fatal error occurred during installation"},
+	1605: {ExitFailure, "the product is not currently installed"},
+	1618: {ExitRetryable, "another installation is already in progress"},
+	1619: {ExitFailure, "the installation package could not be opened"},
+	1620: {ExitFailure, "the installation package is invalid"},
+	1625: {ExitFailure, "the installation is forbidden by system policy"},
+	1633: {ExitFailure, "the installation package is not supported by this processor type"},
+	1638: {ExitFailure, "another version of this product is already installed"},
+	1639: {ExitFailure, "invalid command line argument"},
+	1641: {ExitSuccessRebootRequired, "the installer has initiated a restart"},
+	3010: {ExitSuccessRebootRequired, "a restart is required to complete the installation"},
+}
+
+// exitCodes are the exit codes of each installer type, other than 0 being a success.
+var exitCodes = map[InstallerType]map[int]ExitCode{
+	AdvancedInstaller: msiExitCodes,
+	// https://jrsoftware.org/ishelp/index.php?topic=setupexitcodes
+	InnoSetup: {
+		1: {ExitFailure, "setup failed to initialize"},
+		2: {ExitFailure, "the user cancelled the wizard before installation started"},
+		3: {ExitFailure, "a fatal error occurred while preparing to install"},
+		4: {ExitFailure, "a fatal error occurred during installation"},
+		5: {ExitFailure, "the user cancelled installation"},
+		6: {ExitFailure, "setup was forcefully terminated"},
+		7: {ExitFailure, "setup determined that it cannot proceed with installation"},
+		8: {ExitFailure, "setup cannot proceed with installation until the system is restarted, restart it and try again"},
+	},
+	InstallShield: msiExitCodes,
+	MSI:           msiExitCodes,
+	// https://nsis.sourceforge.io/Docs/AppendixD.html#errorlevels
+	NSIS: {
+		1: {ExitFailure, "installation aborted by the user or failed"},
+		2: {ExitFailure, "installation aborted by the installer"},
+	},
+	// https://sevenzip.osdn.jp/chm/cmdline/exit_codes.htm
+	SevenZipSFX: {
+		1:   {ExitFailure, "some files could not be extracted"},
+		2:   {ExitFailure, "a fatal error occurred during extraction"},
+		7:   {ExitFailure, "invalid command line argument"},
+		8:   {ExitFailure, "not enough memory"},
+		255: {ExitFailure, "extraction was stopped by the user"},
+	},
+	WiXBurn: msiExitCodes,
+}
+
+// InterpretExitCode returns the meaning of the given exit code of an installer of the given type.
+// Exit codes of unknown installer types, and undocumented ones, are a success if 0 and a failure
+// otherwise.
+func InterpretExitCode(installerType InstallerType, code int) ExitCode {
+	if ret, ok := exitCodes[installerType][code]; ok {
+		return ret
+	}
+
+	if code == 0 {
+		return ExitCode{ExitSuccess, ""}
+	}
+
+	return ExitCode{ExitFailure, ""}
+}
@@ -0,0 +1,63 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import "testing"
+
+func TestInterpretExitCode(t *testing.T) {
+	tests := []struct {
+		installerType InstallerType
+		code          int
+		want          ExitStatus
+	}{
+		{MSI, 0, ExitSuccess},
+		{MSI, 1602, ExitFailure},
+		{MSI, 1618, ExitRetryable},
+		{MSI, 1641, ExitSuccessRebootRequired},
+		{MSI, 3010, ExitSuccessRebootRequired},
+		{MSI, 12345, ExitFailure},
+		{AdvancedInstaller, 3010, ExitSuccessRebootRequired},
+		{InstallShield, 1618, ExitRetryable},
+		{WiXBurn, 3010, ExitSuccessRebootRequired},
+		{InnoSetup, 0, ExitSuccess},
+		{InnoSetup, 5, ExitFailure},
+		{InnoSetup, 8, ExitFailure},
+		{NSIS, 0, ExitSuccess},
+		{NSIS, 2, ExitFailure},
+		{NSIS, 3010, ExitFailure},
+		{SevenZipSFX, 255, ExitFailure},
+		{Squirrel, 0, ExitSuccess},
+		{Squirrel, 1, ExitFailure},
+		{"", 0, ExitSuccess},
+		{"", 3010, ExitFailure},
+	}
+
+	for _, test := range tests {
+		if got := InterpretExitCode(test.installerType, test.code); got.Status != test.want {
+			t.Errorf("InterpretExitCode(%q, %v) = %v, want %v", test.installerType, test.code, got.Status, test.want)
+		}
+	}
+}
+
+func TestDocumentedExitCodesHaveDescriptions(t *testing.T) {
+	for installerType, codes := range exitCodes {
+		for code, exitCode := range codes {
+			if code != 0 && exitCode.Description == "" {
+				t.Errorf("%v exit code %v has no description", installerType, code)
+			}
+		}
+	}
+}
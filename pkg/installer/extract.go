@@ -16,58 +16,211 @@
 package installer
 
 import (
-	"archive/zip"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 )
 
-// ExtractZIP extracts the given ZIP archive to the given destination directory. If the destination
-// directory does not exist, it is created.
-func ExtractZIP(path string, dest string) error {
-	if err := os.MkdirAll(dest, 0700); err != nil {
-		return err
+// Default extraction limits, generous enough for any legitimate package while still stopping
+// decompression bombs before they fill the disk.
+const (
+	DefaultMaxExtractEntries = 200000
+	DefaultMaxExtractSize    = 8 << 30 // 8 GiB
+)
+
+// driveLetterRegexp matches Windows paths starting with a drive letter.
+var driveLetterRegexp = regexp.MustCompile(`^[A-Za-z]:`)
+
+// ExtractOptions controls how containers are extracted.
+type ExtractOptions struct {
+	MaxEntries      int   // Maximum number of entries, DefaultMaxExtractEntries if zero
+	MaxSize         int64 // Maximum total uncompressed size in bytes, DefaultMaxExtractSize if zero
+	StripComponents int   // Number of leading path elements removed from entry names, like tar's --strip-components
+}
+
+// LimitError is returned when a container exceeds the extraction limits.
+type LimitError struct {
+	What  string
+	Limit int64
+}
+
+func (l *LimitError) Error() string {
+	return fmt.Sprintf("archive exceeds the limit of %v %v", l.Limit, l.What)
+}
+
+// Extract extracts the container of the given kind (detected from its contents, if empty) at the
+// given path to the given destination directory. If the destination directory does not exist, it
+// is created. Entries whose names are absolute or point outside of the destination directory are
+// rejected. File modes and modification times are preserved, although files are always left
+// writable by their owner so that they can be upgraded or uninstalled later on.
+func Extract(path string, kind ContainerKind, dest string, options *ExtractOptions) error {
+	if options == nil {
+		options = &ExtractOptions{}
 	}
 
-	zipReader, err := zip.OpenReader(path)
+	maxEntries := options.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxExtractEntries
+	}
+
+	maxSize := options.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxExtractSize
+	}
+
+	container, err := OpenContainer(path, kind)
 	if err != nil {
 		return err
 	}
-	defer zipReader.Close()
+	defer container.Close()
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
 
-	for _, zipFile := range zipReader.File {
-		destinationPath := filepath.Join(dest, zipFile.Name)
+	// Directory times are set last, since creating files inside them changes them.
+	type directoryTime struct {
+		path    string
+		modTime time.Time
+	}
+	var directoryTimes []directoryTime
 
-		if zipFile.FileInfo().IsDir() {
-			if err := os.MkdirAll(destinationPath, zipFile.Mode()); err != nil {
-				return err
-			}
-		} else {
-			if err := os.MkdirAll(filepath.Dir(destinationPath), 0700); err != nil {
-				return err
-			}
+	entries := 0
+	remaining := maxSize
 
-			dest, err := os.Create(destinationPath)
-			if err != nil {
-				return err
-			}
-			defer dest.Close()
+	for {
+		entry, contents, err := container.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not read %v: %w", filepath.Base(path), err)
+		}
 
-			source, err := zipFile.Open()
-			if err != nil {
-				return err
-			}
-			defer source.Close()
+		entries++
+		if entries > maxEntries {
+			return &LimitError{"entries", int64(maxEntries)}
+		}
 
-			if _, err := io.Copy(dest, source); err != nil {
-				return err
-			}
+		name, err := safeEntryName(entry.Name, options.StripComponents)
+		if err != nil {
+			return err
+		} else if name == "" {
+			continue // Stripped away entirely
+		}
+
+		destinationPath := filepath.Join(dest, name)
+
+		written, err := writeEntry(destinationPath, entry, contents, remaining)
+		if err != nil {
+			return err
+		}
 
-			// Must explicitly close dest and source at each iteration, defers run too late.
-			dest.Close()
-			source.Close()
+		remaining -= written
+		if remaining < 0 {
+			return &LimitError{"bytes", maxSize}
 		}
+
+		if entry.Mode.IsDir() && !entry.ModTime.IsZero() {
+			directoryTimes = append(directoryTimes, directoryTime{destinationPath, entry.ModTime})
+		}
+	}
+
+	for i := len(directoryTimes) - 1; i >= 0; i-- {
+		os.Chtimes(directoryTimes[i].path, directoryTimes[i].modTime, directoryTimes[i].modTime)
 	}
 
 	return nil
 }
+
+// safeEntryName validates the slash-separated name of a container entry, removes its first
+// stripComponents elements and returns it as a relative path. An empty string is returned if
+// nothing is left after stripping.
+func safeEntryName(name string, stripComponents int) (string, error) {
+	// Archives created on Windows may use backslashes
+	normalized := strings.ReplaceAll(name, "\\", "/")
+
+	if strings.HasPrefix(normalized, "/") || driveLetterRegexp.MatchString(normalized) {
+		return "", fmt.Errorf("archive entry %q has an absolute path", name)
+	}
+
+	var elements []string
+	for _, element := range strings.Split(normalized, "/") {
+		switch element {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("archive entry %q points outside of the destination directory", name)
+		default:
+			if strings.ContainsAny(element, ":*?\"<>|\x00") {
+				return "", fmt.Errorf("archive entry %q contains characters that are not valid in a file name", name)
+			}
+
+			elements = append(elements, element)
+		}
+	}
+
+	if len(elements) <= stripComponents {
+		return "", nil
+	}
+
+	return filepath.Join(elements[stripComponents:]...), nil
+}
+
+// writeEntry writes a single container entry to the given path, writing at most limit+1 bytes, and
+// returns the number of bytes written.
+func writeEntry(destinationPath string, entry *Entry, contents io.Reader, limit int64) (int64, error) {
+	switch {
+	case entry.Mode.IsDir():
+		return 0, os.MkdirAll(destinationPath, entry.Mode.Perm()|0700)
+	case entry.Mode.IsRegular():
+		if contents == nil {
+			return 0, errors.New("programmer error: regular file without contents")
+		}
+
+		if err := os.MkdirAll(filepath.Dir(destinationPath), 0755); err != nil {
+			return 0, err
+		}
+
+		// Replace existing files, even if they are read-only
+		if err := os.Remove(destinationPath); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+
+		f, err := os.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, entry.Mode.Perm()|0600)
+		if err != nil {
+			return 0, err
+		}
+
+		written, err := io.Copy(f, io.LimitReader(contents, limit+1))
+		if err != nil {
+			f.Close()
+			return written, fmt.Errorf("could not extract %v: %w", entry.Name, err)
+		}
+
+		if err := f.Close(); err != nil {
+			return written, err
+		}
+
+		if !entry.ModTime.IsZero() {
+			if err := os.Chtimes(destinationPath, entry.ModTime, entry.ModTime); err != nil {
+				return written, err
+			}
+		}
+
+		return written, nil
+	default:
+		// Links, devices and the like have no place in a Windows installation
+		return 0, nil
+	}
+}
+
+// ExtractZIP extracts the given ZIP archive to the given destination directory. If the destination
+// directory does not exist, it is created.
+func ExtractZIP(path string, dest string) error {
+	return Extract(path, ContainerZIP, dest, nil)
+}
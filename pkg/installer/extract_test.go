@@ -0,0 +1,264 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEntry is an entry of an archive built by buildZIP or buildTar.
+type testEntry struct {
+	name     string
+	contents string
+	mode     os.FileMode
+}
+
+func buildZIP(t *testing.T, entries []testEntry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: time.Date(2020, 5, 17, 13, 37, 42, 0, time.UTC)}
+		if e.mode != 0 {
+			header.SetMode(e.mode)
+		}
+
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(e.contents))
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return writeTestFile(t, "test.zip", buf.Bytes())
+}
+
+func buildTar(t *testing.T, entries []testEntry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: int64(e.mode.Perm()), Size: int64(len(e.contents)), ModTime: time.Date(2020, 5, 17, 13, 37, 42, 0, time.UTC)}
+		switch {
+		case e.mode&os.ModeSymlink != 0:
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = e.contents
+			header.Size = 0
+		case e.mode.IsDir():
+			header.Typeflag = tar.TypeDir
+			header.Size = 0
+		default:
+			header.Typeflag = tar.TypeReg
+		}
+
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			w.Write([]byte(e.contents))
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return writeTestFile(t, "test.tar", buf.Bytes())
+}
+
+func TestSafeEntryName(t *testing.T) {
+	tests := []struct {
+		name            string
+		stripComponents int
+		want            string
+		wantErr         bool
+	}{
+		{"a.txt", 0, "a.txt", false},
+		{"dir/a.txt", 0, filepath.Join("dir", "a.txt"), false},
+		{"dir\\a.txt", 0, filepath.Join("dir", "a.txt"), false},
+		{"./dir//a.txt", 0, filepath.Join("dir", "a.txt"), false},
+		{"app-1.0/bin/app.exe", 1, filepath.Join("bin", "app.exe"), false},
+		{"app-1.0/", 1, "", false},
+		{"app-1.0/bin/app.exe", 3, "", false},
+		{"../a.txt", 0, "", true},
+		{"dir/../../a.txt", 0, "", true},
+		{"dir\\..\\..\\a.txt", 0, "", true},
+		{"/etc/passwd", 0, "", true},
+		{"\\Windows\\evil.dll", 0, "", true},
+		{"C:\\Windows\\evil.dll", 0, "", true},
+		{"c:evil.dll", 0, "", true},
+		{"file.txt:stream", 0, "", true},
+		{"a?.txt", 0, "", true},
+		{"a\x00.txt", 0, "", true},
+	}
+
+	for _, test := range tests {
+		got, err := safeEntryName(test.name, test.stripComponents)
+		if (err != nil) != test.wantErr {
+			t.Errorf("safeEntryName(%q, %v) error = %v, want error %v", test.name, test.stripComponents, err, test.wantErr)
+		} else if got != test.want {
+			t.Errorf("safeEntryName(%q, %v) = %q, want %q", test.name, test.stripComponents, got, test.want)
+		}
+	}
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{"parent directory", "../evil.txt"},
+		{"nested parent directory", "dir/../../evil.txt"},
+		{"backslashes", "dir\\..\\..\\evil.txt"},
+		{"absolute path", "/evil.txt"},
+		{"drive letter", "C:/evil.txt"},
+	}
+
+	for _, test := range tests {
+		for kind, build := range map[string]func(*testing.T, []testEntry) string{"zip": buildZIP, "tar": buildTar} {
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+
+			err := Extract(build(t, []testEntry{{name: "ok.txt", contents: "ok"}, {name: test.entry, contents: "evil"}}), "", dest, nil)
+			if err == nil {
+				t.Errorf("%v (%v): unsafe entry was extracted", test.name, kind)
+			}
+
+			if _, err := os.Stat(filepath.Join(root, "evil.txt")); err == nil {
+				t.Errorf("%v (%v): file was written outside of the destination", test.name, kind)
+			}
+		}
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	entries := []testEntry{
+		{name: "a.txt", contents: strings.Repeat("a", 1000)},
+		{name: "b.txt", contents: strings.Repeat("b", 1000)},
+		{name: "c.txt", contents: strings.Repeat("c", 1000)},
+	}
+
+	tests := []struct {
+		name    string
+		options *ExtractOptions
+		want    *LimitError
+	}{
+		{"defaults", nil, nil},
+		{"entries within limit", &ExtractOptions{MaxEntries: 3}, nil},
+		{"too many entries", &ExtractOptions{MaxEntries: 2}, &LimitError{"entries", 2}},
+		{"size within limit", &ExtractOptions{MaxSize: 3000}, nil},
+		{"too large", &ExtractOptions{MaxSize: 2500}, &LimitError{"bytes", 2500}},
+	}
+
+	path := buildZIP(t, entries)
+
+	for _, test := range tests {
+		err := Extract(path, ContainerZIP, t.TempDir(), test.options)
+
+		var limitErr *LimitError
+		switch {
+		case test.want == nil && err != nil:
+			t.Errorf("%v: %v", test.name, err)
+		case test.want != nil && !errors.As(err, &limitErr):
+			t.Errorf("%v: got %v, want %v", test.name, err, test.want)
+		case test.want != nil && *limitErr != *test.want:
+			t.Errorf("%v: got %v, want %v", test.name, limitErr, test.want)
+		}
+	}
+}
+
+func TestExtractStripComponents(t *testing.T) {
+	path := buildTar(t, []testEntry{
+		{name: "app-1.0/", mode: os.ModeDir | 0755},
+		{name: "app-1.0/bin/", mode: os.ModeDir | 0755},
+		{name: "app-1.0/bin/app.exe", contents: "app", mode: 0755},
+		{name: "app-1.0/README", contents: "readme", mode: 0644},
+	})
+
+	dest := t.TempDir()
+	if err := Extract(path, ContainerTar, dest, &ExtractOptions{StripComponents: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"bin/app.exe": "app", "README": "readme"} {
+		got, err := ioutil.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Error(err)
+		} else if string(got) != want {
+			t.Errorf("%v: got %q, want %q", name, got, want)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, "app-1.0")); err == nil {
+		t.Error("stripped directory was extracted")
+	}
+}
+
+func TestExtractModesAndTimes(t *testing.T) {
+	path := buildTar(t, []testEntry{
+		{name: "dir/", mode: os.ModeDir | 0755},
+		{name: "dir/read-only.txt", contents: "ro", mode: 0444},
+		{name: "dir/link", contents: "/etc/passwd", mode: os.ModeSymlink | 0777},
+	})
+
+	dest := t.TempDir()
+
+	// Extracting twice replaces read-only files
+	for i := 0; i < 2; i++ {
+		if err := Extract(path, ContainerTar, dest, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(dest, "dir", "read-only.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm()&0600 != 0600 {
+		t.Errorf("read-only file has mode %v, want it writable by its owner", info.Mode())
+	}
+
+	want := time.Date(2020, 5, 17, 13, 37, 42, 0, time.UTC)
+	if !info.ModTime().Equal(want) {
+		t.Errorf("file modified at %v, want %v", info.ModTime(), want)
+	}
+
+	if info, err := os.Stat(filepath.Join(dest, "dir")); err != nil {
+		t.Error(err)
+	} else if !info.ModTime().Equal(want) {
+		t.Errorf("directory modified at %v, want %v", info.ModTime(), want)
+	}
+
+	if _, err := os.Lstat(filepath.Join(dest, "dir", "link")); err == nil {
+		t.Error("symbolic link was extracted")
+	}
+}
@@ -15,7 +15,14 @@
 
 package installer
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/just-install/just-install/pkg/cmd"
+)
 
 // InstallerType is a recognized installer type
 type InstallerType string
@@ -23,7 +30,7 @@ type InstallerType string
 // IsValid returns whether the given installer type is known.
 func (it InstallerType) IsValid() bool {
 	switch it {
-	case AdvancedInstaller, Appx, AsIs, InnoSetup, MSI, NSIS, Squirrel:
+	case AdvancedInstaller, Appx, AsIs, InnoSetup, InstallShield, MSI, MSIX, NSIS, SevenZipSFX, Squirrel, WiXBurn:
 		return true
 	default:
 		return false
@@ -36,29 +43,259 @@ const (
 	Appx              InstallerType = "appx"
 	AsIs              InstallerType = "as-is"
 	InnoSetup         InstallerType = "innosetup"
+	InstallShield     InstallerType = "installshield"
 	MSI               InstallerType = "msi"
+	MSIX              InstallerType = "msix"
 	NSIS              InstallerType = "nsis"
+	SevenZipSFX       InstallerType = "7z-sfx"
 	Squirrel          InstallerType = "squirrel"
+	WiXBurn           InstallerType = "wixburn"
+)
+
+// Scope tells whether a package is installed for all users or for the current one only.
+type Scope string
+
+// Installation scopes. An empty scope stands for the default scope of the installer.
+const (
+	ScopeMachine Scope = "machine"
+	ScopeUser    Scope = "user"
 )
 
-// Command returns the command needed to run the given installer of the given type.
-func Command(path string, installerType InstallerType) ([]string, error) {
+// IsValid returns whether the given scope is known. The empty (default) scope is valid.
+func (s Scope) IsValid() bool {
+	return s == "" || s == ScopeMachine || s == ScopeUser
+}
+
+// ScopeError is returned when an installer can't install packages with the requested scope.
+type ScopeError struct {
+	Type  InstallerType
+	Scope Scope
+}
+
+func (s *ScopeError) Error() string {
+	return fmt.Sprintf("%v installers cannot be told to install for scope %q", s.Type, s.Scope)
+}
+
+// CommandOptions are optional settings for the installer command.
+type CommandOptions struct {
+	Destination    string   // Installation directory, if the installer supports choosing it
+	ExtraArguments []string // Appended to the command line
+	LogFile        string   // Where the installer should write its log, if it supports doing so
+	Scope          Scope    // Installation scope, the installer's default if empty
+}
+
+// Command returns the command needed to run the given installer of the given type. Options that the
+// installer type doesn't support are ignored, except for the destination of self-extracting
+// archives, which is required, and the scope, which results in a ScopeError.
+func Command(path string, installerType InstallerType, options *CommandOptions) ([]string, error) {
+	if options == nil {
+		options = &CommandOptions{}
+	}
+
+	if !options.Scope.IsValid() {
+		return nil, fmt.Errorf("unknown scope: %v", options.Scope)
+	}
+
+	ret, err := baseCommand(path, installerType, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// NSIS requires /D= to be the very last argument
+	if installerType == NSIS && options.Destination != "" && len(options.ExtraArguments) > 0 {
+		last := len(ret) - 1
+		return append(append(ret[:last:last], options.ExtraArguments...), ret[last]), nil
+	}
+
+	return append(ret, options.ExtraArguments...), nil
+}
+
+// propertyRegexp matches the PROPERTY=value arguments of Windows Installer and WiX bundles.
+var propertyRegexp = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.]*)=(.*)$`)
+
+// CommandLine returns the Windows command line for the given command of an installer of the given
+// type. Arguments are quoted the usual way, except for the ones installers parse in their own way:
+// the "/D=" argument of NSIS installers must not be quoted at all, the "/v" argument of
+// InstallShield installers must be quoted after "/v", with the quotes it contains escaped, and only
+// the value of the PROPERTY=value arguments of Windows Installer packages and WiX bundles may be
+// quoted, with the quotes it contains doubled.
+func CommandLine(installerType InstallerType, args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = cmd.EscapeArg(arg)
+
+		if i == 0 {
+			continue
+		}
+
+		switch {
+		case installerType == NSIS && i == len(args)-1 && strings.HasPrefix(arg, "/D="):
+			quoted[i] = arg
+		case installerType == InstallShield && strings.HasPrefix(arg, "/v"):
+			quoted[i] = "/v\"" + strings.ReplaceAll(arg[2:], "\"", "\\\"") + "\""
+		case installerType == MSI || installerType == AdvancedInstaller || installerType == WiXBurn:
+			if match := propertyRegexp.FindStringSubmatch(arg); match != nil {
+				quoted[i] = match[1] + "=" + quoteProperty(match[2])
+			}
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// quoteProperty quotes the value of a Windows Installer property, if needed.
+func quoteProperty(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"") {
+		return value
+	}
+
+	return "\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\""
+}
+
+// baseCommand returns the command line of the given installer, without extra arguments.
+func baseCommand(path string, installerType InstallerType, options *CommandOptions) ([]string, error) {
 	switch installerType {
 	case AdvancedInstaller:
-		return []string{path, "/i", "/q"}, nil
-	case Appx:
-		return []string{"powershell.exe", "-command", "Add-AppxPackage -Path " + path}, nil
+		ret := []string{path, "/i", "/q"}
+		if options.Scope == ScopeUser {
+			ret = append(ret, msiPerUserProperties...)
+		}
+
+		if options.Destination != "" {
+			ret = append(ret, "APPDIR="+options.Destination)
+		}
+
+		if options.LogFile != "" {
+			ret = append(ret, "/l*v", options.LogFile)
+		}
+
+		return ret, nil
+	case Appx, MSIX:
+		// Packages are added for the current user, or provisioned for every user of the machine
+		if options.Scope == ScopeMachine {
+			return []string{"powershell.exe", "-command", "Add-AppxProvisionedPackage -Online -SkipLicense -PackagePath " + powershellQuote(path)}, nil
+		}
+
+		return []string{"powershell.exe", "-command", "Add-AppxPackage -Path " + powershellQuote(path)}, nil
 	case AsIs:
+		if options.Scope != "" {
+			return nil, &ScopeError{installerType, options.Scope}
+		}
+
 		return []string{path}, nil
 	case InnoSetup:
-		return []string{path, "/norestart", "/sp-", "/verysilent", "/allusers"}, nil
+		ret := []string{path, "/norestart", "/sp-", "/verysilent", "/allusers"}
+		if options.Scope == ScopeUser {
+			ret[len(ret)-1] = "/currentuser"
+		}
+
+		if options.Destination != "" {
+			ret = append(ret, "/DIR="+options.Destination)
+		}
+
+		if options.LogFile != "" {
+			ret = append(ret, "/LOG="+options.LogFile)
+		}
+
+		return ret, nil
+	case InstallShield:
+		// Everything after /v is passed on to msiexec.exe, as a single argument
+		msiArgs := []string{"/qn"}
+		if options.Scope == ScopeUser {
+			msiArgs = append(msiArgs, msiPerUserProperties...)
+		}
+
+		if options.Destination != "" {
+			msiArgs = append(msiArgs, "INSTALLDIR=\""+options.Destination+"\"")
+		}
+
+		if options.LogFile != "" {
+			msiArgs = append(msiArgs, "/l*v", "\""+options.LogFile+"\"")
+		}
+
+		return []string{path, "/s", "/v" + strings.Join(msiArgs, " ")}, nil
 	case MSI:
-		return []string{"msiexec.exe", "/q", "/i", path, "ALLUSERS=1", "REBOOT=ReallySuppress"}, nil
+		ret := []string{"msiexec.exe", "/q", "/i", path, "ALLUSERS=1", "REBOOT=ReallySuppress"}
+		if options.Scope == ScopeUser {
+			ret = append(append(ret[:4:4], msiPerUserProperties...), "REBOOT=ReallySuppress")
+		}
+
+		if options.Destination != "" {
+			ret = append(ret, "INSTALLDIR="+options.Destination)
+		}
+
+		if options.LogFile != "" {
+			ret = append(ret, "/l*v", options.LogFile)
+		}
+
+		return ret, nil
 	case NSIS:
-		return []string{path, "/S"}, nil
+		// There's no standard way to choose the scope, each installer does it its own way (if at all)
+		if options.Scope != "" {
+			return nil, &ScopeError{installerType, options.Scope}
+		}
+
+		ret := []string{path, "/S"}
+		if options.Destination != "" {
+			// Must be the last argument, and can't be quoted
+			ret = append(ret, "/D="+options.Destination)
+		}
+
+		return ret, nil
+	case SevenZipSFX:
+		// The destination alone decides who can use the extracted files
+		if options.Scope != "" {
+			return nil, &ScopeError{installerType, options.Scope}
+		}
+
+		if options.Destination == "" {
+			return nil, errors.New("self-extracting 7-Zip archives require a destination")
+		}
+
+		return []string{path, "-o" + options.Destination, "-y"}, nil
 	case Squirrel:
+		// Squirrel only ever installs for the current user
+		if options.Scope == ScopeMachine {
+			return nil, &ScopeError{installerType, options.Scope}
+		}
+
 		return []string{path, "--silent"}, nil
+	case WiXBurn:
+		// Bundles define their own variables, if they let users choose the scope at all
+		if options.Scope != "" {
+			return nil, &ScopeError{installerType, options.Scope}
+		}
+
+		ret := []string{path, "/quiet", "/norestart"}
+		if options.Destination != "" {
+			ret = append(ret, "InstallFolder="+options.Destination)
+		}
+
+		if options.LogFile != "" {
+			ret = append(ret, "/log", options.LogFile)
+		}
+
+		return ret, nil
 	default:
 		return nil, errors.New("unknown installer type")
 	}
 }
+
+// msiPerUserProperties are the MSI properties that request a per-user installation.
+var msiPerUserProperties = []string{"ALLUSERS=2", "MSIINSTALLPERUSER=1"}
+
+// powershellQuote quotes the given string for use as a literal in a PowerShell command.
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// MSIUninstallCommand returns the command needed to silently uninstall the MSI package with the given
+// product code, writing a log to logFile if not empty.
+func MSIUninstallCommand(productCode string, logFile string) []string {
+	ret := []string{"msiexec.exe", "/q", "/x", productCode, "REBOOT=ReallySuppress"}
+	if logFile != "" {
+		ret = append(ret, "/l*v", logFile)
+	}
+
+	return ret
+}
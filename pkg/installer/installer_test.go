@@ -0,0 +1,80 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package installer
+
+import "testing"
+
+func TestCommandLine(t *testing.T) {
+	const destination = `C:\Program Files\Example App`
+
+	tests := []struct {
+		installerType InstallerType
+		options       *CommandOptions
+		want          string
+	}{
+		{
+			NSIS,
+			&CommandOptions{Destination: destination},
+			`C:\setup.exe /S /D=C:\Program Files\Example App`,
+		},
+		{
+			NSIS,
+			&CommandOptions{Destination: destination, ExtraArguments: []string{"/NCRC", "/TYPE=full install"}},
+			`C:\setup.exe /S /NCRC "/TYPE=full install" /D=C:\Program Files\Example App`,
+		},
+		{
+			InstallShield,
+			&CommandOptions{Destination: destination, LogFile: `C:\Logs\example app.log`},
+			`C:\setup.exe /s /v"/qn INSTALLDIR=\"C:\Program Files\Example App\" /l*v \"C:\Logs\example app.log\""`,
+		},
+		{
+			InnoSetup,
+			&CommandOptions{Destination: destination},
+			`C:\setup.exe /norestart /sp- /verysilent /allusers "/DIR=C:\Program Files\Example App"`,
+		},
+		{
+			MSI,
+			&CommandOptions{Destination: destination},
+			`msiexec.exe /q /i C:\setup.exe ALLUSERS=1 REBOOT=ReallySuppress INSTALLDIR="C:\Program Files\Example App"`,
+		},
+		{
+			MSI,
+			&CommandOptions{Destination: `C:\Tools`, LogFile: `C:\Logs\example app.log`, ExtraArguments: []string{`COMPANY=Example "Inc"`, "EMPTY=", "/norestart"}},
+			`msiexec.exe /q /i C:\setup.exe ALLUSERS=1 REBOOT=ReallySuppress INSTALLDIR=C:\Tools /l*v "C:\Logs\example app.log" COMPANY="Example ""Inc""" EMPTY="" /norestart`,
+		},
+		{
+			AdvancedInstaller,
+			&CommandOptions{Destination: destination, Scope: ScopeUser},
+			`C:\setup.exe /i /q ALLUSERS=2 MSIINSTALLPERUSER=1 APPDIR="C:\Program Files\Example App"`,
+		},
+		{
+			WiXBurn,
+			&CommandOptions{Destination: destination},
+			`C:\setup.exe /quiet /norestart InstallFolder="C:\Program Files\Example App"`,
+		},
+	}
+
+	for _, test := range tests {
+		args, err := Command(`C:\setup.exe`, test.installerType, test.options)
+		if err != nil {
+			t.Fatalf("%v: %v", test.installerType, err)
+		}
+
+		if got := CommandLine(test.installerType, args); got != test.want {
+			t.Errorf("%v: got\n    %v\nwant\n    %v", test.installerType, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package language
+
+import (
+	"strings"
+)
+
+// Default is the language used when nothing better is available.
+const Default = "en-US"
+
+// mainRegions maps base languages to their main regional variant, for languages where it cannot be
+// obtained by simply upper-casing the base language (e.g. "de" -> "de-DE").
+var mainRegions = map[string]string{
+	"ar": "ar-SA",
+	"ca": "ca-ES",
+	"cs": "cs-CZ",
+	"da": "da-DK",
+	"el": "el-GR",
+	"en": "en-US",
+	"et": "et-EE",
+	"fa": "fa-IR",
+	"he": "he-IL",
+	"hi": "hi-IN",
+	"ja": "ja-JP",
+	"ko": "ko-KR",
+	"ms": "ms-MY",
+	"nb": "nb-NO",
+	"sl": "sl-SI",
+	"sr": "sr-RS",
+	"sv": "sv-SE",
+	"uk": "uk-UA",
+	"vi": "vi-VN",
+	"zh": "zh-CN",
+}
+
+// Normalize converts a language tag to the canonical "ll-RR" form (e.g. "de_ch.UTF-8" -> "de-CH").
+func Normalize(tag string) string {
+	tag = strings.TrimSpace(tag)
+
+	// Drop encoding and modifier of POSIX locales (e.g. "de_CH.UTF-8@euro")
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+
+	parts := strings.Split(strings.Replace(tag, "_", "-", -1), "-")
+	parts[0] = strings.ToLower(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+
+	return strings.Join(parts, "-")
+}
+
+// Base returns the base language of the given tag (e.g. "de" for "de-CH").
+func Base(tag string) string {
+	return strings.SplitN(Normalize(tag), "-", 2)[0]
+}
+
+// FallbackChain returns the languages to try, in order, for the given preferred languages. Each one
+// is followed by the main regional variant of its base language and by the base language itself,
+// with Default added at the end (e.g. "de-CH" -> "de-CH", "de-DE", "de", "en-US").
+func FallbackChain(preferred ...string) []string {
+	var ret []string
+	seen := map[string]bool{}
+
+	add := func(tag string) {
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			ret = append(ret, tag)
+		}
+	}
+
+	for _, tag := range preferred {
+		tag = Normalize(tag)
+		if tag == "" {
+			continue
+		}
+
+		// Bare base languages come after their main regional variant too, since they match any
+		// variant (see Select)
+		base := Base(tag)
+		if tag != base {
+			add(tag)
+		}
+		add(mainRegion(base))
+		add(base)
+	}
+
+	add(Default)
+
+	return ret
+}
+
+// Preferences returns the fallback chain for the given user setting, a comma-separated list of
+// languages, or for the operating system locale if the setting is empty.
+func Preferences(setting string) []string {
+	if strings.TrimSpace(setting) == "" {
+		return FallbackChain(SystemLocale())
+	}
+
+	return FallbackChain(strings.Split(setting, ",")...)
+}
+
+// Select returns the first language of the given chain that is also in the list of supported
+// languages. Base languages of the chain (e.g. "de") also match any supported regional variant of
+// theirs (e.g. "de-AT"), before moving on to the next language of the chain. If there is no match,
+// it falls back to the first supported language and false is returned.
+func Select(chain []string, supported []string) (string, bool) {
+	for _, tag := range chain {
+		for _, candidate := range supported {
+			if strings.EqualFold(Normalize(candidate), tag) {
+				return candidate, true
+			}
+		}
+
+		if strings.Contains(tag, "-") {
+			continue
+		}
+
+		for _, candidate := range supported {
+			if Base(candidate) == tag {
+				return candidate, true
+			}
+		}
+	}
+
+	if len(supported) > 0 {
+		return supported[0], false
+	}
+
+	return "", false
+}
+
+// mainRegion returns the main regional variant of the given base language.
+func mainRegion(base string) string {
+	if ret, ok := mainRegions[base]; ok {
+		return ret
+	}
+
+	if len(base) != 2 {
+		return ""
+	}
+
+	return base + "-" + strings.ToUpper(base)
+}
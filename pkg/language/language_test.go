@@ -0,0 +1,97 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package language
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"de-CH", "de-CH"},
+		{"de_ch", "de-CH"},
+		{"de_CH.UTF-8@euro", "de-CH"},
+		{" EN-us ", "en-US"},
+		{"zh-Hant-TW", "zh-Hant-TW"},
+		{"fr", "fr"},
+	}
+
+	for _, test := range tests {
+		if got := Normalize(test.tag); got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.tag, got, test.want)
+		}
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	tests := []struct {
+		preferred []string
+		want      []string
+	}{
+		{[]string{"de-CH"}, []string{"de-CH", "de-DE", "de", "en-US"}},
+		{[]string{"de_CH.UTF-8"}, []string{"de-CH", "de-DE", "de", "en-US"}},
+		{[]string{"sv-FI", "fr"}, []string{"sv-FI", "sv-SE", "sv", "fr-FR", "fr", "en-US"}},
+		{[]string{"en-GB"}, []string{"en-GB", "en-US", "en"}},
+		{[]string{"", " "}, []string{"en-US"}},
+		{nil, []string{"en-US"}},
+	}
+
+	for _, test := range tests {
+		if got := FallbackChain(test.preferred...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("FallbackChain(%q) = %q, want %q", test.preferred, got, test.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name      string
+		preferred []string
+		supported []string
+		want      string
+		wantOK    bool
+	}{
+		{"exact", []string{"de-CH"}, []string{"en-US", "de-DE", "de-CH"}, "de-CH", true},
+		{"main region before en-US", []string{"de-CH"}, []string{"en-US", "de-DE"}, "de-DE", true},
+		{"base language before en-US", []string{"de-CH"}, []string{"en-US", "de"}, "de", true},
+		{"same base before en-US", []string{"de-CH"}, []string{"en-US", "de-AT"}, "de-AT", true},
+		{"case of supported languages is kept", []string{"de-CH"}, []string{"en-us", "DE_de"}, "DE_de", true},
+		{"en-US", []string{"de-CH"}, []string{"fr-FR", "en-US"}, "en-US", true},
+		{"second preference before en-US", []string{"de-CH", "fr-CH"}, []string{"en-US", "fr-FR"}, "fr-FR", true},
+		{"first preference wins", []string{"de-CH", "fr-CH"}, []string{"fr-FR", "de-AT"}, "de-AT", true},
+		{"bare preference prefers the main region", []string{"fr"}, []string{"fr-CA", "fr-FR"}, "fr-FR", true},
+		{"bare preference matches any region", []string{"fr"}, []string{"en-US", "fr-CA"}, "fr-CA", true},
+		{"nothing matches", []string{"de-CH"}, []string{"fr-FR", "it-IT"}, "fr-FR", false},
+		{"nothing supported", []string{"de-CH"}, nil, "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := Select(FallbackChain(test.preferred...), test.supported)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("%v: got %q, %v, want %q, %v", test.name, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	if got, want := Preferences("de-CH, fr"), []string{"de-CH", "de-DE", "de", "fr-FR", "fr", "en-US"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
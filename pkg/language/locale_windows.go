@@ -0,0 +1,39 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package language
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// localeNameMaxLength is LOCALE_NAME_MAX_LENGTH from winnls.h.
+const localeNameMaxLength = 85
+
+var procGetUserDefaultLocaleName = syscall.NewLazyDLL("kernel32.dll").NewProc("GetUserDefaultLocaleName")
+
+// SystemLocale returns the locale of the current user, or an empty string if it cannot be
+// determined.
+func SystemLocale() string {
+	buf := make([]uint16, localeNameMaxLength)
+
+	ret, _, _ := procGetUserDefaultLocaleName.Call(uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if ret == 0 {
+		return ""
+	}
+
+	return Normalize(syscall.UTF16ToString(buf))
+}
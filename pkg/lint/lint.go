@@ -0,0 +1,117 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lint
+
+import (
+	"sort"
+
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// Finding is a problem found by a rule in a package.
+type Finding struct {
+	Rule    string `json:"rule"`
+	Package string `json:"package"`
+	Message string `json:"message"`
+}
+
+// Rule is a named check that is run against each package of a registry.
+type Rule struct {
+	Name        string
+	Description string
+	check       func(pkg *packageView) []string
+}
+
+// Options that influence Run.
+type Options struct {
+	Disabled []string // Names of the rules that must not be run.
+}
+
+// Rules returns all the known rules, sorted by name.
+func Rules() []*Rule {
+	ret := []*Rule{
+		checksumWithLangRule,
+		containerInstallerRule,
+		destinationOutsideProgramFilesRule,
+		identicalURLsRule,
+		langWithoutLanguagesRule,
+		plainHTTPRule,
+		shimOutsideDestinationRule,
+		shortcutOutsideFolderRule,
+		unknownContainerKindRule,
+		unknownInstallerKindRule,
+		undefinedVariableRule,
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+
+	return ret
+}
+
+// IsValidRule returns whether a rule with the given name exists.
+func IsValidRule(name string) bool {
+	for _, rule := range Rules() {
+		if rule.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Run runs all enabled rules against all packages of the given registry. Packages can opt out of
+// single rules by listing them in their "lintIgnore" field. Findings are sorted by package name
+// first and by rule name second.
+func Run(registry *registry4.Registry, options *Options) []*Finding {
+	if options == nil {
+		options = &Options{}
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range options.Disabled {
+		disabled[name] = true
+	}
+
+	ret := []*Finding{}
+
+	for _, name := range registry.SortedPackageNames() {
+		pkg := newPackageView(name, registry.Packages[name])
+
+		ignored := map[string]bool{}
+		for _, rule := range pkg.LintIgnore {
+			ignored[rule] = true
+		}
+
+		for _, rule := range Rules() {
+			if disabled[rule.Name] || ignored[rule.Name] {
+				continue
+			}
+
+			seen := map[string]bool{}
+			for _, message := range rule.check(pkg) {
+				// Flat options are checked once per architecture, report each problem only once.
+				if seen[message] {
+					continue
+				}
+				seen[message] = true
+
+				ret = append(ret, &Finding{Rule: rule.Name, Package: name, Message: message})
+			}
+		}
+	}
+
+	return ret
+}
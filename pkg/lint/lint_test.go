@@ -0,0 +1,129 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lint
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  string
+		want []string
+	}{
+		{
+			"clean",
+			`{"version": "1", "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe"}}`,
+			nil,
+		},
+		{
+			"container detected from its contents",
+			`{"version": "1", "installer": {"kind": "msi", "x86": "https://example.com/setup.zip", "options": {"container": {"installer": "setup.msi"}}}}`,
+			nil,
+		},
+		{
+			"container detected from its contents, URL without extension",
+			`{"version": "1", "installer": {"kind": "msi", "x86": "https://example.com/download?file=setup.msi", "options": {"container": {"installer": "setup.msi"}}}}`,
+			nil,
+		},
+		{
+			"container of an explicit kind",
+			`{"version": "1", "installer": {"kind": "msi", "x86": "https://example.com/setup.exe", "options": {"container": {"installer": "setup.msi", "kind": "7z"}}}}`,
+			nil,
+		},
+		{
+			"container installer without container",
+			`{"version": "1", "installer": {"kind": "msi", "x86": "https://example.com/setup.msi", "x86_64": "https://example.com/setup-x64.exe?mirror=1", "options": {"container": {"installer": "setup.msi"}}}}`,
+			[]string{"container-installer-without-container"},
+		},
+		{
+			"container installer without container, ignored",
+			`{"version": "1", "lintIgnore": ["container-installer-without-container"], "installer": {"kind": "msi", "x86": "https://example.com/setup.msi", "options": {"container": {"installer": "setup.msi"}}}}`,
+			nil,
+		},
+		{
+			"unknown container kind",
+			`{"version": "1", "installer": {"kind": "msi", "x86": "https://example.com/setup.rar", "options": {"container": {"installer": "setup.msi", "kind": "rar"}}}}`,
+			[]string{"unknown-container-kind"},
+		},
+		{
+			"plain http and identical urls",
+			`{"version": "1", "installer": {"kind": "nsis", "x86": "http://example.com/setup.exe", "x86_64": "http://example.com/setup.exe"}}`,
+			[]string{"identical-urls", "plain-http"},
+		},
+		{
+			"checksum with lang",
+			`{"version": "1", "languages": ["en", "de"], "installer": {"kind": "nsis", "x86": "https://example.com/setup-{{.lang}}.exe", "checksums": {"x86": "00"}}}`,
+			[]string{"checksum-with-lang"},
+		},
+		{
+			"shortcut outside folder",
+			`{"version": "1", "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "options": {"shortcuts": [{"name": "..\\..\\Startup\\Example", "target": "example.exe"}, {"name": "Example", "folder": "{{.APPDATA}}", "target": "example.exe"}]}}}`,
+			[]string{"shortcut-outside-folder", "shortcut-outside-folder"},
+		},
+		{
+			"undefined variable",
+			`{"version": "1", "installer": {"kind": "nsis", "x86": "https://example.com/setup-{{.nope}}.exe"}}`,
+			[]string{"undefined-variable"},
+		},
+		{
+			"install commands",
+			`{"version": "1", "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "options": {"preInstall": [{"arguments": ["unblock", "{{.installer}}"]}], "postInstall": [{"arguments": ["configure", "{{.installer}}", "{{.destination}}"]}]}}}`,
+			[]string{"undefined-variable"},
+		},
+	}
+
+	for _, test := range tests {
+		var pkg registry4.Package
+		if err := json.Unmarshal([]byte(test.pkg), &pkg); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		registry := &registry4.Registry{Version: registry4.FormatVersion, Packages: registry4.PackageMap{"example": &pkg}}
+
+		var got []string
+		for _, finding := range Run(registry, nil) {
+			got = append(got, finding.Rule)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestIsValidRule(t *testing.T) {
+	for _, rule := range Rules() {
+		if !IsValidRule(rule.Name) {
+			t.Errorf("rule %v is not valid", rule.Name)
+		}
+	}
+
+	for _, name := range []string{"container-installer-without-container", "unknown-container-kind"} {
+		if !IsValidRule(name) {
+			t.Errorf("rule %v is missing", name)
+		}
+	}
+
+	if IsValidRule("no-such-rule") {
+		t.Error("unknown rule is valid")
+	}
+}
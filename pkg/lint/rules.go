@@ -0,0 +1,496 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lint
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/just-install/just-install/pkg/architecture"
+	"github.com/just-install/just-install/pkg/installer"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// knownEnvironmentVariables are the environment variables that can be safely referenced by
+// templates, as normalized by just-install (upper case, "(x86)" replaced by "_X86").
+var knownEnvironmentVariables = map[string]bool{
+	"ALLUSERSPROFILE":        true,
+	"APPDATA":                true,
+	"COMMONPROGRAMFILES":     true,
+	"COMMONPROGRAMFILES_X86": true,
+	"COMMONPROGRAMW6432":     true,
+	"COMPUTERNAME":           true,
+	"HOMEDRIVE":              true,
+	"HOMEPATH":               true,
+	"LOCALAPPDATA":           true,
+	"PROGRAMDATA":            true,
+	"PROGRAMFILES":           true,
+	"PROGRAMFILES_X86":       true,
+	"PROGRAMW6432":           true,
+	"PUBLIC":                 true,
+	"SYSTEMDRIVE":            true,
+	"SYSTEMROOT":             true,
+	"TEMP":                   true,
+	"TMP":                    true,
+	"USERNAME":               true,
+	"USERPROFILE":            true,
+	"WINDIR":                 true,
+}
+
+// templateSpaceRegexp matches spaces around template actions, for normalization purposes.
+var templateSpaceRegexp = regexp.MustCompile(`{{\s*(.*?)\s*}}`)
+
+// templateFields returns the names of the top-level fields (i.e. variables) referenced by the given
+// template string.
+func templateFields(s string) ([]string, error) {
+	trees, err := parse.Parse("lint", s, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var ret []string
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if !seen[n.Ident[0]] {
+				seen[n.Ident[0]] = true
+				ret = append(ret, n.Ident[0])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+
+	for _, tree := range trees {
+		walk(tree.Root)
+	}
+
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+// normalizePath normalizes a templated Windows path for comparison purposes.
+func normalizePath(s string) string {
+	s = templateSpaceRegexp.ReplaceAllString(s, "{{$1}}")
+	s = strings.Replace(s, "/", "\\", -1)
+	s = strings.TrimRight(s, "\\")
+
+	return strings.ToLower(s)
+}
+
+// isBelow returns whether the given templated path is below the given templated directory.
+func isBelow(path string, dir string) bool {
+	return strings.HasPrefix(normalizePath(path), normalizePath(dir)+"\\")
+}
+
+var unknownInstallerKindRule = &Rule{
+	Name:        "unknown-installer-kind",
+	Description: "installer kinds must be known to just-install",
+	check: func(pkg *packageView) []string {
+		kind := pkg.Installer.Kind
+
+		switch {
+		case kind == "copy" || kind == "custom":
+		case installer.ContainerKind(kind).IsValid():
+		case installer.InstallerType(kind).IsValid():
+		default:
+			return []string{fmt.Sprintf("unknown installer kind %q", kind)}
+		}
+
+		return nil
+	},
+}
+
+var checksumWithLangRule = &Rule{
+	Name:        "checksum-with-lang",
+	Description: "checksums cannot be verified for installers whose URL depends on {{.lang}}",
+	check: func(pkg *packageView) []string {
+		if pkg.Installer == nil {
+			return nil
+		}
+
+		var ret []string
+
+		for _, arch := range []string{architecture.X86, architecture.X86_64} {
+			url, ok := pkg.URLs[arch]
+			if _, hasChecksum := pkg.Installer.Checksums[arch]; !ok || !hasChecksum {
+				continue
+			}
+
+			fields, err := templateFields(url)
+			if err != nil {
+				continue
+			}
+
+			for _, field := range fields {
+				if field == "lang" {
+					ret = append(ret, fmt.Sprintf("%v checksum is given but the installer URL uses {{.lang}}", arch))
+					break
+				}
+			}
+		}
+
+		return ret
+	},
+}
+
+// installerExtensions are the extensions of files that are installers rather than containers.
+var installerExtensions = map[string]bool{
+	".appx":       true,
+	".appxbundle": true,
+	".exe":        true,
+	".msi":        true,
+	".msix":       true,
+	".msixbundle": true,
+}
+
+// isInstallerURL returns whether the given URL points to an installer, judging from its extension.
+func isInstallerURL(s string) bool {
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	return installerExtensions[strings.ToLower(path.Ext(parsed.Path))]
+}
+
+var containerInstallerRule = &Rule{
+	Name:        "container-installer-without-container",
+	Description: "\"container.installer\" is set but the package downloads an installer, not a container",
+	check: func(pkg *packageView) []string {
+		// An explicit container kind says otherwise, as does any URL that may point to a container
+		urls := pkg.sortedURLs()
+		if len(urls) == 0 {
+			return nil
+		}
+
+		for _, u := range urls {
+			if !isInstallerURL(u) {
+				return nil
+			}
+		}
+
+		var ret []string
+
+		for _, options := range pkg.sortedOptions() {
+			if options.Container != nil && options.Container.Installer != "" && options.Container.Kind == "" {
+				ret = append(ret, fmt.Sprintf("\"container.installer\" is %q but the package has no container", options.Container.Installer))
+			}
+		}
+
+		return ret
+	},
+}
+
+var unknownContainerKindRule = &Rule{
+	Name:        "unknown-container-kind",
+	Description: "\"container.kind\" must be a supported archive format, or be left out to detect it",
+	check: func(pkg *packageView) []string {
+		var ret []string
+
+		for _, options := range pkg.sortedOptions() {
+			if options.Container != nil && options.Container.Kind != "" && !installer.ContainerKind(options.Container.Kind).IsValid() {
+				ret = append(ret, fmt.Sprintf("unknown container kind %q", options.Container.Kind))
+			}
+		}
+
+		return ret
+	},
+}
+
+var destinationOutsideProgramFilesRule = &Rule{
+	Name:        "destination-outside-programfiles",
+	Description: "archive packages must be extracted below {{.PROGRAMFILES}} or {{.PROGRAMFILES_X86}}",
+	check: func(pkg *packageView) []string {
+		if !installer.ContainerKind(pkg.Installer.Kind).IsValid() {
+			return nil
+		}
+
+		var ret []string
+
+		for _, options := range pkg.sortedOptions() {
+			if options.Destination == "" {
+				continue
+			}
+
+			if !isBelow(options.Destination, "{{.PROGRAMFILES}}") && !isBelow(options.Destination, "{{.PROGRAMFILES_X86}}") {
+				ret = append(ret, fmt.Sprintf("destination %v is not below {{.PROGRAMFILES}}", options.Destination))
+			}
+		}
+
+		return ret
+	},
+}
+
+var identicalURLsRule = &Rule{
+	Name:        "identical-urls",
+	Description: "the x86_64 URL is the same as the x86 one and should be omitted",
+	check: func(pkg *packageView) []string {
+		if pkg.Installer.X86 != "" && pkg.Installer.X86 == pkg.Installer.X86_64 {
+			return []string{"x86_64 URL is the same as the x86 one"}
+		}
+
+		return nil
+	},
+}
+
+var langWithoutLanguagesRule = &Rule{
+	Name:        "lang-without-languages",
+	Description: "{{.lang}} is used by a package that does not declare its supported languages",
+	check: func(pkg *packageView) []string {
+		if len(pkg.Languages) > 0 {
+			return nil
+		}
+
+		for _, url := range pkg.sortedURLs() {
+			fields, err := templateFields(url)
+			if err != nil {
+				continue
+			}
+
+			for _, field := range fields {
+				if field == "lang" {
+					return []string{"{{.lang}} is used but \"languages\" is empty"}
+				}
+			}
+		}
+
+		return nil
+	},
+}
+
+var plainHTTPRule = &Rule{
+	Name:        "plain-http",
+	Description: "installers must be downloaded over HTTPS",
+	check: func(pkg *packageView) []string {
+		var ret []string
+
+		for _, url := range pkg.sortedURLs() {
+			if strings.HasPrefix(strings.ToLower(url), "http://") {
+				ret = append(ret, fmt.Sprintf("%v is not an HTTPS URL", url))
+			}
+		}
+
+		return ret
+	},
+}
+
+var shimOutsideDestinationRule = &Rule{
+	Name:        "shim-outside-destination",
+	Description: "shims of archive and copy packages must point below the destination",
+	check: func(pkg *packageView) []string {
+		if !installer.ContainerKind(pkg.Installer.Kind).IsValid() && pkg.Installer.Kind != "copy" {
+			return nil
+		}
+
+		var ret []string
+
+		for _, options := range pkg.sortedOptions() {
+			if options.Destination == "" {
+				continue
+			}
+
+			dir := options.Destination
+			if pkg.Installer.Kind == "copy" {
+				dir = dir[:strings.LastIndexAny(dir, "\\/")+1]
+			}
+
+			for _, shim := range options.Shims {
+				if normalizePath(shim) != normalizePath(options.Destination) && !isBelow(shim, dir) {
+					ret = append(ret, fmt.Sprintf("shim %v is outside of %v", shim, options.Destination))
+				}
+			}
+		}
+
+		return ret
+	},
+}
+
+var shortcutOutsideFolderRule = &Rule{
+	Name:        "shortcut-outside-folder",
+	Description: "shortcut names must be plain file names and folders must stay below the Start Menu",
+	check: func(pkg *packageView) []string {
+		var ret []string
+
+		for _, options := range pkg.sortedOptions() {
+			for _, shortcut := range options.Shortcuts {
+				if !registry4.IsShortcutName(shortcut.Name) {
+					ret = append(ret, fmt.Sprintf("shortcut name %q is not a plain file name", shortcut.Name))
+				}
+
+				if !registry4.IsShortcutFolder(shortcut.Folder) {
+					ret = append(ret, fmt.Sprintf("shortcut folder %q is not below the Start Menu", shortcut.Folder))
+				}
+
+				// Environment variables hold paths, which would place the shortcut anywhere
+				if fields, err := templateFields(shortcut.Name); err == nil && len(fields) > 0 {
+					ret = append(ret, fmt.Sprintf("shortcut name %q references variables", shortcut.Name))
+				}
+
+				if fields, err := templateFields(shortcut.Folder); err == nil && len(fields) > 0 {
+					ret = append(ret, fmt.Sprintf("shortcut folder %q references variables", shortcut.Folder))
+				}
+			}
+		}
+
+		return ret
+	},
+}
+
+var undefinedVariableRule = &Rule{
+	Name:        "undefined-variable",
+	Description: "templates must only reference variables that are defined when they are expanded",
+	check: func(pkg *packageView) []string {
+		var ret []string
+
+		checkTemplate := func(what string, s string, variables ...string) {
+			fields, err := templateFields(s)
+			if err != nil {
+				ret = append(ret, fmt.Sprintf("%v %q is not a valid template: %v", what, s, err))
+				return
+			}
+
+			for _, field := range fields {
+				if knownEnvironmentVariables[field] || contains(variables, field) {
+					continue
+				}
+
+				ret = append(ret, fmt.Sprintf("%v %q references undefined variable %q", what, s, field))
+			}
+		}
+
+		for _, url := range pkg.sortedURLs() {
+			checkTemplate("URL", url, "version", "lang")
+		}
+
+		for _, options := range pkg.sortedOptions() {
+			for _, argument := range options.Arguments {
+				checkTemplate("argument", argument, "installer")
+			}
+
+			checkTemplate("destination", options.Destination)
+
+			for _, argument := range options.ExtraArguments {
+				checkTemplate("extra argument", argument)
+			}
+
+			for _, hook := range options.PreInstall {
+				for _, argument := range hook.Arguments {
+					checkTemplate("pre-install argument", argument, "installer")
+				}
+			}
+
+			for _, hook := range options.PostInstall {
+				for _, argument := range hook.Arguments {
+					checkTemplate("post-install argument", argument, "installer")
+				}
+			}
+
+			for _, dir := range options.Path {
+				checkTemplate("PATH entry", dir)
+			}
+
+			variables := make([]string, 0, len(options.Environment))
+			for variable := range options.Environment {
+				variables = append(variables, variable)
+			}
+			sort.Strings(variables)
+
+			for _, variable := range variables {
+				checkTemplate("environment variable "+variable, options.Environment[variable])
+			}
+
+			for _, shim := range options.Shims {
+				checkTemplate("shim", shim)
+			}
+
+			for _, shortcut := range options.Shortcuts {
+				checkTemplate("shortcut name", shortcut.Name)
+				checkTemplate("shortcut target", shortcut.Target)
+				checkTemplate("shortcut description", shortcut.Description)
+				checkTemplate("shortcut folder", shortcut.Folder)
+				checkTemplate("shortcut icon", shortcut.Icon)
+				checkTemplate("shortcut working directory", shortcut.WorkingDirectory)
+
+				for _, argument := range shortcut.Arguments {
+					checkTemplate("shortcut argument", argument)
+				}
+			}
+
+			if options.Uninstall != nil {
+				for _, argument := range options.Uninstall.Arguments {
+					checkTemplate("uninstall argument", argument, "destination")
+				}
+			}
+		}
+
+		return ret
+	},
+}
+
+// contains returns whether the given slice contains the given string.
+func contains(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
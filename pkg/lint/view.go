@@ -0,0 +1,97 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lint
+
+import (
+	"github.com/just-install/just-install/pkg/architecture"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/strings2"
+)
+
+// packageView is a package along with the data that most rules need, computed once.
+type packageView struct {
+	*registry4.Package
+	Name    string
+	URLs    map[string]string             // Installer URLs as declared, keyed by architecture.
+	Options map[string]*registry4.Options // Options that would be used on each architecture.
+}
+
+// newPackageView creates the view of the given package.
+func newPackageView(name string, pkg *registry4.Package) *packageView {
+	ret := &packageView{
+		Package: pkg,
+		Name:    name,
+		URLs:    map[string]string{},
+		Options: map[string]*registry4.Options{},
+	}
+
+	if pkg.Installer == nil {
+		return ret
+	}
+
+	if strings2.IsNotEmpty(pkg.Installer.X86) {
+		ret.URLs[architecture.X86] = pkg.Installer.X86
+	}
+
+	if strings2.IsNotEmpty(pkg.Installer.X86_64) {
+		ret.URLs[architecture.X86_64] = pkg.Installer.X86_64
+	}
+
+	if len(pkg.Installer.Options) == 0 {
+		return ret
+	}
+
+	// The 32-bit installer is used on 64-bit machines if needed, so options for both architectures
+	// are relevant as soon as there is a 32-bit installer.
+	for _, arch := range []string{architecture.X86, architecture.X86_64} {
+		if _, ok := ret.URLs[arch]; !ok && ret.URLs[architecture.X86] == "" {
+			continue
+		}
+
+		// Options that cannot be decoded are a validation problem, not a lint one.
+		if options, err := pkg.Installer.OptionsForArch(arch); err == nil {
+			ret.Options[arch] = options
+		}
+	}
+
+	return ret
+}
+
+// sortedOptions returns the options of the package in a stable order (x86 first).
+func (p *packageView) sortedOptions() []*registry4.Options {
+	var ret []*registry4.Options
+
+	for _, arch := range []string{architecture.X86, architecture.X86_64} {
+		if options, ok := p.Options[arch]; ok {
+			ret = append(ret, options)
+		}
+	}
+
+	return ret
+}
+
+// sortedURLs returns the URLs of the package in a stable order (x86 first).
+func (p *packageView) sortedURLs() []string {
+	var ret []string
+
+	for _, arch := range []string{architecture.X86, architecture.X86_64} {
+		if url, ok := p.URLs[arch]; ok {
+			ret = append(ret, url)
+		}
+	}
+
+	return ret
+}
@@ -23,7 +23,7 @@ import (
 // TempFileCreate is the same as TempFile() but also creates just-install's temporary directory if
 // missing.
 func TempFileCreate(file string) (string, error) {
-	if err := os.MkdirAll(tempDir(), 0700); err != nil {
+	if err := os.MkdirAll(TempDir(), 0700); err != nil {
 		return "", err
 	}
 
@@ -33,7 +33,7 @@ func TempFileCreate(file string) (string, error) {
 // TempDirCreate is the same as TempDir() but also creates just-install's temporary directory if
 // missing.
 func TempDirCreate() (string, error) {
-	ret := tempDir()
+	ret := TempDir()
 
 	if err := os.MkdirAll(ret, 0700); err != nil {
 		return "", err
@@ -42,12 +42,44 @@ func TempDirCreate() (string, error) {
 	return ret, nil
 }
 
+// DataDir returns the per-machine directory where just-install keeps its state, creating it if
+// needed. It is "%ProgramData%\just-install" unless overridden by the JUST_INSTALL_DATA_DIR
+// environment variable.
+func DataDir() (string, error) {
+	ret := dataDir()
+
+	if err := os.MkdirAll(ret, 0755); err != nil {
+		return "", err
+	}
+
+	return ret, nil
+}
+
+func dataDir() string {
+	if ret := os.Getenv("JUST_INSTALL_DATA_DIR"); ret != "" {
+		return ret
+	}
+
+	if programData := os.Getenv("ProgramData"); programData != "" {
+		return filepath.Join(programData, "just-install")
+	}
+
+	// Not on Windows, most likely during development
+	return filepath.Join(TempDir(), "data")
+}
+
+// LogDir returns the directory where the logs of the installers of the given package are kept. It
+// is not created.
+func LogDir(pkg string) string {
+	return filepath.Join(dataDir(), "logs", pkg)
+}
+
 // tempFile returns the path to a temporary file below just-install's temporary file directory.
 func tempFile(file string) string {
-	return filepath.Join(tempDir(), file)
+	return filepath.Join(TempDir(), file)
 }
 
-// tempDir returns the temporary directory that must be used to store all of just-install's files.
-func tempDir() string {
+// TempDir returns the temporary directory that must be used to store all of just-install's files.
+func TempDir() string {
 	return filepath.Join(os.TempDir(), "just-install")
 }
@@ -0,0 +1,168 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry4
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Changes describes the differences between two registries.
+type Changes struct {
+	Added   []*PackageChange `json:"added"`
+	Removed []*PackageChange `json:"removed"`
+	Changed []*PackageChange `json:"changed"`
+}
+
+// IsEmpty returns whether the two compared registries contain the same packages.
+func (c *Changes) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// PackageChange describes how a single package changed between two registries. Added packages only
+// have NewVersion set, removed packages only have OldVersion set.
+type PackageChange struct {
+	Name              string `json:"name"`
+	OldVersion        string `json:"oldVersion,omitempty"`
+	NewVersion        string `json:"newVersion,omitempty"`
+	VersionChanged    bool   `json:"versionChanged,omitempty"`
+	KindChanged       bool   `json:"kindChanged,omitempty"`
+	URLChanged        bool   `json:"urlChanged,omitempty"`
+	OptionsChanged    bool   `json:"optionsChanged,omitempty"`
+	ChecksumsChanged  bool   `json:"checksumsChanged,omitempty"`
+	AliasesChanged    bool   `json:"aliasesChanged,omitempty"`
+	DeprecatedChanged bool   `json:"deprecatedChanged,omitempty"`
+}
+
+// HasChanges returns whether anything changed in the package.
+func (c *PackageChange) HasChanges() bool {
+	return c.VersionChanged || c.KindChanged || c.URLChanged || c.OptionsChanged || c.ChecksumsChanged ||
+		c.AliasesChanged || c.DeprecatedChanged
+}
+
+// Diff compares two registries, returning the packages that were added, removed or changed going
+// from oldRegistry to newRegistry. Each list is sorted by package name.
+func Diff(oldRegistry *Registry, newRegistry *Registry) *Changes {
+	ret := &Changes{
+		Added:   []*PackageChange{},
+		Removed: []*PackageChange{},
+		Changed: []*PackageChange{},
+	}
+
+	for _, name := range oldRegistry.SortedPackageNames() {
+		if _, ok := newRegistry.Packages[name]; !ok {
+			ret.Removed = append(ret.Removed, &PackageChange{
+				Name:       name,
+				OldVersion: oldRegistry.Packages[name].Version,
+			})
+		}
+	}
+
+	for _, name := range newRegistry.SortedPackageNames() {
+		newPackage := newRegistry.Packages[name]
+
+		oldPackage, ok := oldRegistry.Packages[name]
+		if !ok {
+			ret.Added = append(ret.Added, &PackageChange{Name: name, NewVersion: newPackage.Version})
+			continue
+		}
+
+		change := diffPackage(name, oldPackage, newPackage)
+		if change.HasChanges() {
+			ret.Changed = append(ret.Changed, change)
+		}
+	}
+
+	sort.Slice(ret.Added, func(i, j int) bool { return ret.Added[i].Name < ret.Added[j].Name })
+	sort.Slice(ret.Removed, func(i, j int) bool { return ret.Removed[i].Name < ret.Removed[j].Name })
+
+	return ret
+}
+
+// diffPackage compares two versions of the same package.
+func diffPackage(name string, oldPackage *Package, newPackage *Package) *PackageChange {
+	ret := &PackageChange{
+		Name:           name,
+		OldVersion:     oldPackage.Version,
+		NewVersion:     newPackage.Version,
+		VersionChanged: oldPackage.Version != newPackage.Version,
+	}
+
+	ret.AliasesChanged = !sameStrings(oldPackage.Aliases, newPackage.Aliases)
+	ret.DeprecatedChanged = !reflect.DeepEqual(oldPackage.Deprecated, newPackage.Deprecated)
+
+	oldInstaller := oldPackage.Installer
+	newInstaller := newPackage.Installer
+	if oldInstaller == nil || newInstaller == nil {
+		ret.KindChanged = oldInstaller != newInstaller
+		return ret
+	}
+
+	ret.KindChanged = oldInstaller.Kind != newInstaller.Kind
+	ret.URLChanged = oldInstaller.X86 != newInstaller.X86 || oldInstaller.X86_64 != newInstaller.X86_64
+	ret.OptionsChanged = !sameJSON(oldInstaller.Options, newInstaller.Options)
+	ret.ChecksumsChanged = !sameChecksums(oldInstaller.Checksums, newInstaller.Checksums)
+
+	return ret
+}
+
+// sameStrings returns whether the given lists contain the same strings, in any order.
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	return reflect.DeepEqual(sortedA, sortedB)
+}
+
+// sameChecksums returns whether the given checksums are the same, treating nil and empty maps alike
+// and ignoring case.
+func sameChecksums(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for arch, checksum := range a {
+		if other, ok := b[arch]; !ok || !strings.EqualFold(checksum, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameJSON returns whether the given values have the same JSON representation, treating nil and
+// empty maps alike.
+func sameJSON(a map[string]interface{}, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+
+	aBytes, aErr := json.Marshal(a)
+	bBytes, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return reflect.DeepEqual(a, b)
+	}
+
+	return string(aBytes) == string(bBytes)
+}
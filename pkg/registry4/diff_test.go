@@ -0,0 +1,96 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry4
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	const base = `{"version": "1.0", "aliases": ["a", "b"], "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "checksums": {"x86": "abcd"}, "options": {"destination": "x"}}}`
+
+	tests := []struct {
+		name    string
+		changed string
+		want    PackageChange
+	}{
+		{"same", base, PackageChange{}},
+		{"aliases reordered", `{"version": "1.0", "aliases": ["b", "a"], "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "checksums": {"x86": "ABCD"}, "options": {"destination": "x"}}}`, PackageChange{}},
+		{"version", `{"version": "1.1", "aliases": ["a", "b"], "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "checksums": {"x86": "abcd"}, "options": {"destination": "x"}}}`, PackageChange{VersionChanged: true}},
+		{"kind", `{"version": "1.0", "aliases": ["a", "b"], "installer": {"kind": "innosetup", "x86": "https://example.com/setup.exe", "checksums": {"x86": "abcd"}, "options": {"destination": "x"}}}`, PackageChange{KindChanged: true}},
+		{"url", `{"version": "1.0", "aliases": ["a", "b"], "installer": {"kind": "nsis", "x86": "https://example.org/setup.exe", "checksums": {"x86": "abcd"}, "options": {"destination": "x"}}}`, PackageChange{URLChanged: true}},
+		{"options", `{"version": "1.0", "aliases": ["a", "b"], "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "checksums": {"x86": "abcd"}, "options": {"destination": "y"}}}`, PackageChange{OptionsChanged: true}},
+		{"checksums", `{"version": "1.0", "aliases": ["a", "b"], "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "checksums": {"x86": "ef01"}, "options": {"destination": "x"}}}`, PackageChange{ChecksumsChanged: true}},
+		{"aliases", `{"version": "1.0", "aliases": ["a"], "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "checksums": {"x86": "abcd"}, "options": {"destination": "x"}}}`, PackageChange{AliasesChanged: true}},
+		{"deprecated", `{"version": "1.0", "aliases": ["a", "b"], "deprecated": {"replacement": "other"}, "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "checksums": {"x86": "abcd"}, "options": {"destination": "x"}}}`, PackageChange{DeprecatedChanged: true}},
+	}
+
+	for _, test := range tests {
+		oldRegistry := testRegistry(t, base)
+		newRegistry := testRegistry(t, test.changed)
+
+		changes := Diff(oldRegistry, newRegistry)
+		if len(changes.Added) > 0 || len(changes.Removed) > 0 {
+			t.Errorf("%v: got added %v, removed %v", test.name, changes.Added, changes.Removed)
+		}
+
+		var got PackageChange
+		if len(changes.Changed) > 0 {
+			got = *changes.Changed[0]
+			got.Name, got.OldVersion, got.NewVersion = "", "", ""
+		}
+
+		if got != test.want {
+			t.Errorf("%v: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiffAddedRemoved(t *testing.T) {
+	oldRegistry := &Registry{Packages: PackageMap{"kept": {Version: "1"}, "removed": {Version: "2"}}}
+	newRegistry := &Registry{Packages: PackageMap{"kept": {Version: "1"}, "b-added": {Version: "3"}, "a-added": {Version: "4"}}}
+
+	changes := Diff(oldRegistry, newRegistry)
+
+	if len(changes.Added) != 2 || changes.Added[0].Name != "a-added" || changes.Added[1].Name != "b-added" {
+		t.Errorf("got added %+v", changes.Added)
+	}
+
+	if len(changes.Removed) != 1 || changes.Removed[0].Name != "removed" || changes.Removed[0].OldVersion != "2" {
+		t.Errorf("got removed %+v", changes.Removed)
+	}
+
+	if len(changes.Changed) != 0 {
+		t.Errorf("got changed %+v", changes.Changed)
+	}
+
+	if !Diff(oldRegistry, oldRegistry).IsEmpty() {
+		t.Error("registry differs from itself")
+	}
+}
+
+// testRegistry returns a registry with a single package, "example", decoded from the given JSON.
+func testRegistry(t *testing.T, pkg string) *Registry {
+	t.Helper()
+
+	var ret Package
+	if err := json.Unmarshal([]byte(pkg), &ret); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Registry{Version: FormatVersion, Packages: PackageMap{"example": &ret}}
+}
@@ -0,0 +1,102 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry4
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/just-install/just-install/pkg/strings2"
+)
+
+// Match is the result of looking up a package by name.
+type Match struct {
+	Name    string   // Canonical name of the package.
+	Package *Package // The package itself.
+	Alias   string   // The alias that matched, if the package was found through one of its aliases.
+}
+
+// Lookup finds a package by name or alias. Exact matches take precedence over case-insensitive ones,
+// and package names take precedence over aliases.
+func (r *Registry) Lookup(name string) (*Match, bool) {
+	if pkg, ok := r.Packages[name]; ok {
+		return &Match{Name: name, Package: pkg}, true
+	}
+
+	for _, candidate := range r.SortedPackageNames() {
+		if strings.EqualFold(candidate, name) {
+			return &Match{Name: candidate, Package: r.Packages[candidate]}, true
+		}
+	}
+
+	for _, exact := range []bool{true, false} {
+		for _, candidate := range r.SortedPackageNames() {
+			pkg := r.Packages[candidate]
+
+			for _, alias := range pkg.Aliases {
+				if alias == name || (!exact && strings.EqualFold(alias, name)) {
+					return &Match{Name: candidate, Package: pkg, Alias: alias}, true
+				}
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Suggest returns up to max package names (or aliases) that are close to the given, unknown, name,
+// closest first. It is meant to be used to build "did you mean" messages.
+func (r *Registry) Suggest(name string, max int) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	name = strings.ToLower(name)
+
+	// Allow roughly one typo every three characters, but at least two.
+	threshold := len(name) / 3
+	if threshold < 2 {
+		threshold = 2
+	}
+
+	var suggestions []suggestion
+	for _, candidate := range r.SortedPackageNames() {
+		names := append([]string{candidate}, r.Packages[candidate].Aliases...)
+
+		for _, n := range names {
+			distance := strings2.EditDistance(name, strings.ToLower(n))
+			if distance <= threshold {
+				suggestions = append(suggestions, suggestion{n, distance})
+			}
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	var ret []string
+	for _, s := range suggestions {
+		if len(ret) >= max {
+			break
+		}
+
+		ret = append(ret, s.name)
+	}
+
+	return ret
+}
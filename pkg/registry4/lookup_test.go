@@ -0,0 +1,103 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry4
+
+import (
+	"reflect"
+	"testing"
+)
+
+// lookupRegistry returns a registry with packages whose names and aliases overlap in case.
+func lookupRegistry() *Registry {
+	return &Registry{
+		Version: FormatVersion,
+		Packages: PackageMap{
+			"7zip":          {Version: "1", Aliases: []string{"7-zip", "sevenzip"}},
+			"firefox":       {Version: "1", Aliases: []string{"mozilla-firefox", "FF"}},
+			"Firefox-ESR":   {Version: "1", Aliases: []string{"ff"}},
+			"notepad++":     {Version: "1", Aliases: []string{"npp"}},
+			"python3":       {Version: "1", Aliases: []string{"python"}},
+			"python2":       {Version: "1"},
+			"vlc":           {Version: "1"},
+			"visual-studio": {Version: "1", Aliases: []string{"vs"}},
+		},
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantName  string
+		wantAlias string
+		wantOK    bool
+	}{
+		{"firefox", "firefox", "", true},
+		{"FIREFOX", "firefox", "", true},
+		{"firefox-esr", "Firefox-ESR", "", true},
+		{"sevenzip", "7zip", "sevenzip", true},
+		{"7-ZIP", "7zip", "7-zip", true},
+		{"python", "python3", "python", true},
+		{"ff", "Firefox-ESR", "ff", true}, // Exact alias beats case-insensitive one
+		{"FF", "firefox", "FF", true},     // Even if the other package sorts first
+		{"Ff", "Firefox-ESR", "ff", true}, // Case-insensitive aliases are tried in package order
+		{"npp", "notepad++", "npp", true},
+		{"chrome", "", "", false},
+		{"", "", "", false},
+	}
+
+	r := lookupRegistry()
+
+	for _, test := range tests {
+		match, ok := r.Lookup(test.name)
+		if ok != test.wantOK {
+			t.Errorf("Lookup(%q) found = %v, want %v", test.name, ok, test.wantOK)
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if match.Name != test.wantName || match.Alias != test.wantAlias || match.Package != r.Packages[test.wantName] {
+			t.Errorf("Lookup(%q) = %v (alias %q), want %v (alias %q)", test.name, match.Name, match.Alias, test.wantName, test.wantAlias)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int
+		want []string
+	}{
+		{"firefx", 3, []string{"firefox"}},
+		{"Pyhton3", 3, []string{"python3"}},                    // A transposition is two edits
+		{"pyton", 3, []string{"python", "python2", "python3"}}, // Ties in package order
+		{"pyton", 1, []string{"python"}},
+		{"vlcc", 3, []string{"vlc"}},
+		{"visualstudio", 3, []string{"visual-studio"}},
+		{"libreoffice", 3, nil},
+		{"vx", 3, []string{"vs", "ff", "FF"}},
+	}
+
+	r := lookupRegistry()
+
+	for _, test := range tests {
+		if got := r.Suggest(test.name, test.max); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Suggest(%q, %v) = %q, want %q", test.name, test.max, got, test.want)
+		}
+	}
+}
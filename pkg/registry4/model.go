@@ -19,18 +19,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/just-install/just-install/pkg/architecture"
 )
 
+// FormatVersion is the value of the "version" field of v4 registries.
+const FormatVersion = 4
+
 // PackageMap maps package names to their associated metadata.
 type PackageMap map[string]*Package
 
 // Registry represents a package registry.
 type Registry struct {
-	Packages PackageMap `json:"packages"`
-	Schema   string     `json:"$schema"`
+	Schema   string     `json:"$schema,omitempty"`
 	Version  int        `json:"version"`
+	Packages PackageMap `json:"packages"`
 }
 
 // SortedPackageNames returns the list of packages present in the registry, sorted alphabetically.
@@ -48,22 +52,48 @@ func (r *Registry) SortedPackageNames() []string {
 
 // Package represents a single package.
 type Package struct {
-	Installer *Installer `json:"installer"`
-	SkipAudit bool       `json:"skipAudit,omitempty"`
-	Version   string     `json:"version"`
+	Aliases    []string     `json:"aliases,omitempty"` // Alternate names resolving to this package
+	Checkver   *Checkver    `json:"checkver,omitempty"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	Installer  *Installer   `json:"installer"`
+	Languages  []string     `json:"languages,omitempty"`  // Values accepted by the "lang" template variable
+	LintIgnore []string     `json:"lintIgnore,omitempty"` // Lint rules that must not be run on this package
+	SkipAudit  bool         `json:"skipAudit,omitempty"`
+	Version    string       `json:"version"`
+}
+
+// Checkver describes how to find the latest upstream version of a package. Either GitHub or URL must
+// be set. The document at URL is searched with JSONPath (if set) and then with Regex (if set),
+// whose first capturing group (or whole match) is the version.
+type Checkver struct {
+	GitHub   string `json:"github,omitempty"`   // "owner/repository", the latest release tag is used
+	JSONPath string `json:"jsonPath,omitempty"` // Dot-separated path into a JSON document (e.g. "data.0.version")
+	Regex    string `json:"regex,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// Deprecation marks a package as deprecated, optionally pointing to the package that replaces it.
+type Deprecation struct {
+	Message     string `json:"message,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
 }
 
 // Installer contains information to fetch and execute the installer for a package.
 type Installer struct {
-	Kind    string                 `json:"kind"`
-	Options map[string]interface{} `json:"options,omitempty"`
-	X86     string                 `json:"x86,omitempty"`
-	X86_64  string                 `json:"x86_64,omitempty"`
+	Checksums map[string]string      `json:"checksums,omitempty"` // SHA-256 of the installers, keyed by architecture
+	Kind      string                 `json:"kind"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	X86       string                 `json:"x86,omitempty"`
+	X86_64    string                 `json:"x86_64,omitempty"`
 }
 
+// Architectures are the architectures that installers can be declared for in this format. The other
+// architectures, such as ARM64, need the v5 format.
+var Architectures = []string{architecture.X86, architecture.X86_64}
+
 // OptionsForArch returns the options object for the given architecture
 func (i *Installer) OptionsForArch(arch string) (*Options, error) {
-	if !architecture.IsValid(arch) {
+	if !isArchitecture(arch) {
 		return nil, fmt.Errorf("invalid architecture: %v", arch)
 	}
 
@@ -73,7 +103,7 @@ func (i *Installer) OptionsForArch(arch string) (*Options, error) {
 	// Check whether we have some architecture-specific options...
 	optionsKeysLUT := map[string]bool{}
 
-	for _, arch := range architecture.Architectures() {
+	for _, arch := range Architectures {
 		_, ok := i.Options[arch]
 		optionsKeysLUT[arch] = ok
 	}
@@ -114,13 +144,106 @@ func (i *Installer) OptionsForArch(arch string) (*Options, error) {
 	return ret, nil
 }
 
+// isArchitecture returns whether the given architecture is one of Architectures.
+func isArchitecture(arch string) bool {
+	for _, v := range Architectures {
+		if v == arch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetOptions replaces the options of the installer with the given ones, which apply to all
+// architectures.
+func (i *Installer) SetOptions(options *Options) error {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Errorf("could not perform intermediate marshal on options object: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("could not unmarshal options object: %w", err)
+	}
+
+	i.Options = raw
+
+	return nil
+}
+
 // Options are the options that can be used to customise the install process of a package.
 type Options struct {
-	Arguments   []string    `json:"arguments,omitempty"`
-	Container   *Container  `json:"container,omitempty"`
-	Destination string      `json:"destination,omitempty"`
-	Shims       []string    `json:"shims,omitempty"`
-	Shortcuts   []*Shortcut `json:"shortcuts,omitempty"`
+	Arguments   []string   `json:"arguments,omitempty"`
+	Container   *Container `json:"container,omitempty"`
+	Destination string     `json:"destination,omitempty"`
+
+	// Environment lists the environment variables set once the package is installed, and Path the
+	// directories added to PATH. Values are templates, like Destination.
+	Environment map[string]string `json:"environment,omitempty"`
+	Path        []string          `json:"path,omitempty"`
+
+	// ExtraArguments are appended to the command line of installers with a known kind (e.g. to
+	// choose features of MSI packages with "ADDLOCAL=...").
+	ExtraArguments []string `json:"extraArguments,omitempty"`
+
+	// PreInstall and PostInstall are commands run before and after the installer, with the same
+	// variables as Arguments.
+	PreInstall  []*Hook `json:"preInstall,omitempty"`
+	PostInstall []*Hook `json:"postInstall,omitempty"`
+
+	Shims     []string    `json:"shims,omitempty"`
+	Shortcuts []*Shortcut `json:"shortcuts,omitempty"`
+	Uninstall *Uninstall  `json:"uninstall,omitempty"`
+
+	// StripComponents is the number of leading directories removed from the paths of archive
+	// entries, so that archives with a versioned top-level directory can be extracted straight
+	// into Destination.
+	StripComponents int `json:"stripComponents,omitempty"`
+
+	// Timeout is how long the installer may run before it is killed, as a Go duration such as
+	// "30m". It overrides the default timeout.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Hook is a command run before or after the installer of a package.
+type Hook struct {
+	Arguments    []string `json:"arguments"`
+	SuccessCodes []int    `json:"successCodes,omitempty"` // Exit codes meaning success, only 0 if empty
+}
+
+// IsSuccess returns whether the given exit code of the hook means success.
+func (h *Hook) IsSuccess(code int) bool {
+	if len(h.SuccessCodes) == 0 {
+		return code == 0
+	}
+
+	for _, successCode := range h.SuccessCodes {
+		if code == successCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TimeoutDuration returns the parsed Timeout, or zero if it isn't set.
+func (o *Options) TimeoutDuration() (time.Duration, error) {
+	if o.Timeout == "" {
+		return 0, nil
+	}
+
+	ret, err := time.ParseDuration(o.Timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	if ret <= 0 {
+		return 0, fmt.Errorf("timeout must be positive: %v", o.Timeout)
+	}
+
+	return ret, nil
 }
 
 // Container represents options to run an installer wrapped inside a container format.
@@ -129,8 +252,26 @@ type Container struct {
 	Kind      string `json:"kind"`
 }
 
-// Shortcut represents a shortcut to a program that is created after an installer has run.
+// Uninstall describes how to remove a package installed by an installer that just-install can't undo
+// by itself. MSI packages need their product code, other installers the command line of their
+// uninstaller, which can refer to the installation directory with {{.destination}}.
+type Uninstall struct {
+	Arguments   []string `json:"arguments,omitempty"`
+	ProductCode string   `json:"productCode,omitempty"`
+}
+
+// Shortcut represents a shortcut to a program that is created after an installer has run. It goes
+// in the Start Menu, in Folder if set, or on the desktop. All fields but Desktop and IconIndex are
+// templates, like Destination.
 type Shortcut struct {
 	Name   string `json:"name"`
 	Target string `json:"target"`
+
+	Arguments        []string `json:"arguments,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	Desktop          bool     `json:"desktop,omitempty"`
+	Folder           string   `json:"folder,omitempty"` // Start Menu subfolder, may be nested
+	Icon             string   `json:"icon,omitempty"`
+	IconIndex        int      `json:"iconIndex,omitempty"`
+	WorkingDirectory string   `json:"workingDirectory,omitempty"`
 }
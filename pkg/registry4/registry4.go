@@ -17,18 +17,24 @@ package registry4
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
-	"os"
+	"strings"
+
+	"github.com/just-install/just-install/pkg/dataformat"
+	"github.com/just-install/just-install/pkg/strings2"
 )
 
-// Load lods a registry file at the given path.
+// Load lods a registry file at the given path. The file can be in any of the formats supported by
+// the dataformat package, detected from its extension or contents.
 func Load(path string) (*Registry, error) {
-	f, err := os.Open(path)
+	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	b, err := ioutil.ReadAll(f)
+	b, err = dataformat.ToJSON(b, dataformat.Detect(path, b))
 	if err != nil {
 		return nil, err
 	}
@@ -38,5 +44,139 @@ func Load(path string) (*Registry, error) {
 		return nil, err
 	}
 
+	if err := Validate(ret); err != nil {
+		return nil, err
+	}
+
 	return ret, nil
 }
+
+// Validate checks that the given registry is well-formed.
+func Validate(r *Registry) error {
+	if r.Version != FormatVersion {
+		return fmt.Errorf("expected registry version %v but found %v", FormatVersion, r.Version)
+	}
+
+	// Aliases share the namespace of package names, regardless of case.
+	names := map[string]string{}
+	for name := range r.Packages {
+		names[strings.ToLower(name)] = name
+	}
+
+	for _, name := range r.SortedPackageNames() {
+		pkg := r.Packages[name]
+		if pkg == nil {
+			return fmt.Errorf("%v: package entry is empty", name)
+		}
+
+		for _, alias := range pkg.Aliases {
+			if other, ok := names[strings.ToLower(alias)]; ok {
+				return fmt.Errorf("%v: alias %v clashes with %v", name, alias, other)
+			}
+
+			names[strings.ToLower(alias)] = name
+		}
+
+		if pkg.Deprecated != nil && pkg.Deprecated.Replacement != "" {
+			if _, ok := r.Packages[pkg.Deprecated.Replacement]; !ok {
+				return fmt.Errorf("%v: replacement package %v does not exist", name, pkg.Deprecated.Replacement)
+			}
+		}
+
+		if pkg.Installer == nil {
+			return fmt.Errorf("%v: package entry is missing \"installer\"", name)
+		}
+
+		if strings2.IsEmpty(pkg.Installer.Kind) {
+			return fmt.Errorf("%v: installer is missing \"kind\"", name)
+		}
+
+		if strings2.IsEmpty(pkg.Installer.X86) && strings2.IsEmpty(pkg.Installer.X86_64) {
+			return fmt.Errorf("%v: package entry is missing both 32-bit and 64-bit installers", name)
+		}
+
+		for _, arch := range Architectures {
+			// Missing options for one of the architectures are only a problem when installing
+			options, _ := pkg.Installer.OptionsForArch(arch)
+			if options == nil {
+				continue
+			}
+
+			if err := options.Validate(); err != nil {
+				return fmt.Errorf("%v: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the values of the given options which can be checked without installing the
+// package.
+func (o *Options) Validate() error {
+	if o.StripComponents < 0 {
+		return errors.New("\"stripComponents\" must not be negative")
+	}
+
+	if _, err := o.TimeoutDuration(); err != nil {
+		return fmt.Errorf("invalid \"timeout\": %w", err)
+	}
+
+	for _, hook := range append(o.PreInstall, o.PostInstall...) {
+		if hook == nil || len(hook.Arguments) == 0 {
+			return errors.New("\"preInstall\" and \"postInstall\" commands must have \"arguments\"")
+		}
+	}
+
+	for variable := range o.Environment {
+		if strings2.IsEmpty(variable) || strings.Contains(variable, "=") {
+			return fmt.Errorf("invalid environment variable name: %q", variable)
+		}
+
+		if strings.EqualFold(variable, "PATH") {
+			return errors.New("PATH cannot be set, use \"path\" to add directories to it")
+		}
+	}
+
+	for _, shortcut := range o.Shortcuts {
+		if shortcut == nil || strings2.IsEmpty(shortcut.Name) || strings2.IsEmpty(shortcut.Target) {
+			return errors.New("shortcuts must have a \"name\" and a \"target\"")
+		}
+
+		if shortcut.Desktop && shortcut.Folder != "" {
+			return fmt.Errorf("shortcut %v: desktop shortcuts cannot have a \"folder\"", shortcut.Name)
+		}
+
+		if !IsShortcutName(shortcut.Name) {
+			return fmt.Errorf("shortcut %v: \"name\" must be a file name, without folders", shortcut.Name)
+		}
+
+		if !IsShortcutFolder(shortcut.Folder) {
+			return fmt.Errorf("shortcut %v: \"folder\" must be a relative path within the Start Menu: %v", shortcut.Name, shortcut.Folder)
+		}
+	}
+
+	return nil
+}
+
+// IsShortcutName returns whether the given shortcut name is a plain file name, which cannot place
+// the shortcut anywhere but in its folder.
+func IsShortcutName(name string) bool {
+	return name != "." && name != ".." && !strings.ContainsAny(name, "\\/:")
+}
+
+// IsShortcutFolder returns whether the given Start Menu folder stays below the Start Menu. Folders
+// are Windows paths, whatever the platform just-install runs on.
+func IsShortcutFolder(folder string) bool {
+	if strings.HasPrefix(folder, "\\") || strings.HasPrefix(folder, "/") || strings.Contains(folder, ":") {
+		return false
+	}
+
+	for _, component := range strings.FieldsFunc(folder, func(r rune) bool { return r == '\\' || r == '/' }) {
+		if component == ".." {
+			return false
+		}
+	}
+
+	return true
+}
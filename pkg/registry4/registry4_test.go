@@ -0,0 +1,153 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry4
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsShortcutName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Example", true},
+		{"Example {{.version}}", true},
+		{"Example 1.0", true},
+		{".", false},
+		{"..", false},
+		{"..\\Example", false},
+		{"Tools/Example", false},
+		{"C:Example", false},
+	}
+
+	for _, test := range tests {
+		if got := IsShortcutName(test.name); got != test.want {
+			t.Errorf("IsShortcutName(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestIsShortcutFolder(t *testing.T) {
+	tests := []struct {
+		folder string
+		want   bool
+	}{
+		{"", true},
+		{"Example", true},
+		{"Example\\Tools", true},
+		{"Example/Tools", true},
+		{"..", false},
+		{"Example\\..\\..\\Startup", false},
+		{"\\Example", false},
+		{"/Example", false},
+		{"C:\\Example", false},
+	}
+
+	for _, test := range tests {
+		if got := IsShortcutFolder(test.folder); got != test.want {
+			t.Errorf("IsShortcutFolder(%q) = %v, want %v", test.folder, got, test.want)
+		}
+	}
+}
+
+func TestHookIsSuccess(t *testing.T) {
+	tests := []struct {
+		successCodes []int
+		code         int
+		want         bool
+	}{
+		{nil, 0, true},
+		{nil, 1, false},
+		{[]int{0, 3010}, 3010, true},
+		{[]int{0, 3010}, 0, true},
+		{[]int{1}, 1, true},
+		{[]int{1}, 0, false},
+	}
+
+	for _, test := range tests {
+		if got := (&Hook{Arguments: []string{"setup"}, SuccessCodes: test.successCodes}).IsSuccess(test.code); got != test.want {
+			t.Errorf("success codes %v, exit code %v: got %v, want %v", test.successCodes, test.code, got, test.want)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr string
+	}{
+		{"empty", Options{}, ""},
+		{"install commands", Options{PreInstall: []*Hook{{Arguments: []string{"unblock"}}}, PostInstall: []*Hook{{Arguments: []string{"configure"}}}}, ""},
+		{"pre-install without arguments", Options{PreInstall: []*Hook{{}}}, "must have \"arguments\""},
+		{"null post-install command", Options{PostInstall: []*Hook{nil}}, "must have \"arguments\""},
+		{"negative stripComponents", Options{StripComponents: -1}, "must not be negative"},
+		{"invalid timeout", Options{Timeout: "soon"}, "invalid \"timeout\""},
+		{"invalid environment variable", Options{Environment: map[string]string{"A=B": "C"}}, "invalid environment variable name"},
+		{"PATH", Options{Environment: map[string]string{"Path": "C:\\Example"}}, "PATH cannot be set"},
+	}
+
+	for _, test := range tests {
+		err := test.options.Validate()
+		if test.wantErr == "" && err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		} else if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+			t.Errorf("%v: got error %v, want %q", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestOptionsForArch(t *testing.T) {
+	shared := &Installer{Options: map[string]interface{}{"shims": []interface{}{"tool.exe"}}}
+	specific := &Installer{Options: map[string]interface{}{
+		"x86":    map[string]interface{}{"shims": []interface{}{"tool32.exe"}},
+		"x86_64": map[string]interface{}{"shims": []interface{}{"tool64.exe"}},
+	}}
+	arm64Only := &Installer{Options: map[string]interface{}{
+		"arm64": map[string]interface{}{"shims": []interface{}{"tool-arm64.exe"}},
+		"shims": []interface{}{"tool.exe"},
+	}}
+
+	tests := []struct {
+		name      string
+		installer *Installer
+		arch      string
+		want      string // First shim, empty if an error is expected
+	}{
+		{"shared options on x86", shared, "x86", "tool.exe"},
+		{"shared options on x86_64", shared, "x86_64", "tool.exe"},
+		{"x86 options", specific, "x86", "tool32.exe"},
+		{"x86_64 options", specific, "x86_64", "tool64.exe"},
+		{"arm64 is a v5 architecture", shared, "arm64", ""},
+		{"arm64 options are not architecture-specific", arm64Only, "x86_64", "tool.exe"},
+		{"unknown architecture", shared, "mips", ""},
+	}
+
+	for _, test := range tests {
+		options, err := test.installer.OptionsForArch(test.arch)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%v: expected an error", test.name)
+			}
+		} else if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		} else if len(options.Shims) == 0 || options.Shims[0] != test.want {
+			t.Errorf("%v: got shims %v, want %v", test.name, options.Shims, test.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry4
+
+import (
+	"fmt"
+
+	"github.com/just-install/just-install/internal/atomicfile"
+	"github.com/just-install/just-install/pkg/dataformat"
+)
+
+// New creates an empty registry.
+func New() *Registry {
+	return &Registry{Packages: PackageMap{}, Version: FormatVersion}
+}
+
+// Set adds the given package to the registry, replacing any package with the same name. The
+// registry is left untouched if the result would not be valid.
+func (r *Registry) Set(name string, pkg *Package) error {
+	if name == "" {
+		return fmt.Errorf("package name cannot be empty")
+	}
+
+	packages := r.copyPackages()
+	packages[name] = pkg
+
+	return r.replacePackages(packages)
+}
+
+// Remove removes the package with the given name from the registry. The registry is left untouched
+// if the result would not be valid (e.g. if another package names it as its replacement).
+func (r *Registry) Remove(name string) error {
+	if _, ok := r.Packages[name]; !ok {
+		return fmt.Errorf("unknown package: %v", name)
+	}
+
+	packages := r.copyPackages()
+	delete(packages, name)
+
+	return r.replacePackages(packages)
+}
+
+// Marshal encodes the registry in the given format. The output is canonical (see
+// dataformat.Marshal), so that saving a registry that has not changed yields the same bytes.
+func (r *Registry) Marshal(format dataformat.Format) ([]byte, error) {
+	return dataformat.Marshal(r, format)
+}
+
+// Save writes the registry to the given path, in the format matching its extension (JSON if
+// unknown). The file is replaced atomically.
+func (r *Registry) Save(path string) error {
+	format, ok := dataformat.FromExtension(path)
+	if !ok {
+		format = dataformat.JSON
+	}
+
+	return r.SaveAs(path, format)
+}
+
+// SaveAs is the same as Save, in the given format whatever the extension of the file.
+func (r *Registry) SaveAs(path string, format dataformat.Format) error {
+	b, err := r.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(path, b, 0644)
+}
+
+// copyPackages returns a shallow copy of the package map.
+func (r *Registry) copyPackages() PackageMap {
+	ret := make(PackageMap, len(r.Packages)+1)
+
+	for k, v := range r.Packages {
+		ret[k] = v
+	}
+
+	return ret
+}
+
+// replacePackages replaces the package map, if the result is a valid registry.
+func (r *Registry) replacePackages(packages PackageMap) error {
+	candidate := *r
+	candidate.Packages = packages
+
+	if err := Validate(&candidate); err != nil {
+		return err
+	}
+
+	r.Packages = packages
+
+	return nil
+}
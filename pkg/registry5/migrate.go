@@ -0,0 +1,399 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry5
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/just-install/just-install/pkg/architecture"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/strings2"
+)
+
+// FromV4 converts a v4 registry to the v5 format. The conversion fails, instead of silently dropping
+// information, if any package contains data that has no place in the v5 model.
+func FromV4(r *registry4.Registry) (*Registry, error) {
+	ret := &Registry{Packages: PackageMap{}, Version: FormatVersion}
+
+	for _, name := range r.SortedPackageNames() {
+		pkg, err := PackageFromV4(r.Packages[name])
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+
+		ret.Packages[name] = pkg
+	}
+
+	return ret, nil
+}
+
+// PackageFromV4 converts a single v4 package to the v5 format.
+func PackageFromV4(pkg *registry4.Package) (*Package, error) {
+	if pkg == nil || pkg.Installer == nil {
+		return nil, errors.New("package entry is missing \"installer\"")
+	}
+
+	urls := map[string]string{
+		architecture.X86:    pkg.Installer.X86,
+		architecture.X86_64: pkg.Installer.X86_64,
+	}
+
+	archSpecificOptions := hasArchitectureSpecificOptions(pkg.Installer)
+
+	// Checksums are keyed by the architecture of the URL they refer to.
+	checksumKeys := map[string]string{
+		architecture.X86:    architecture.X86,
+		architecture.X86_64: architecture.X86_64,
+	}
+
+	// v4 installs the 32-bit installer on 64-bit machines when the latter is missing, but still
+	// picks the 64-bit options. Make that explicit by duplicating the installer if needed.
+	if strings2.IsEmpty(urls[architecture.X86_64]) && archSpecificOptions {
+		if _, ok := pkg.Installer.Options[architecture.X86_64]; ok {
+			urls[architecture.X86_64] = urls[architecture.X86]
+			checksumKeys[architecture.X86_64] = architecture.X86
+		}
+	}
+
+	ret := &Package{
+		Aliases:    pkg.Aliases,
+		Checkver:   pkg.Checkver,
+		Deprecated: pkg.Deprecated,
+		Installers: InstallerMap{},
+		Languages:  pkg.Languages,
+		LintIgnore: pkg.LintIgnore,
+		SkipAudit:  pkg.SkipAudit,
+		Version:    pkg.Version,
+	}
+
+	for _, arch := range registry4.Architectures {
+		if strings2.IsEmpty(urls[arch]) {
+			if _, ok := pkg.Installer.Options[arch]; ok && archSpecificOptions {
+				return nil, fmt.Errorf("options are given for %v but there is no installer for it", arch)
+			}
+
+			continue
+		}
+
+		installer := &Installer{
+			Checksum: pkg.Installer.Checksums[checksumKeys[arch]],
+			Kind:     pkg.Installer.Kind,
+			URL:      urls[arch],
+		}
+
+		if len(pkg.Installer.Options) > 0 {
+			options, err := pkg.Installer.OptionsForArch(arch)
+			if err != nil {
+				return nil, err
+			}
+
+			var raw interface{} = pkg.Installer.Options
+			if archSpecificOptions {
+				raw = pkg.Installer.Options[arch]
+			}
+
+			if err := checkRoundTrip(raw, options); err != nil {
+				return nil, fmt.Errorf("options for %v: %w", arch, err)
+			}
+
+			installer.Options = options
+		}
+
+		ret.Installers[arch] = installer
+	}
+
+	if len(ret.Installers) == 0 {
+		return nil, errors.New("package entry is missing both 32-bit and 64-bit installers")
+	}
+
+	return ret, nil
+}
+
+// ToV4 converts a v5 registry to the v4 format. See PackageToV4 for details.
+func ToV4(r *Registry) (*registry4.Registry, error) {
+	ret := &registry4.Registry{
+		Packages: registry4.PackageMap{},
+		Schema:   r.Schema,
+		Version:  registry4.FormatVersion,
+	}
+
+	for _, name := range r.SortedPackageNames() {
+		pkg, err := PackageToV4(r.Packages[name])
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+
+		ret.Packages[name] = pkg
+	}
+
+	return ret, nil
+}
+
+// PackageToV4 converts a single v5 package to the v4 format. Installers for architectures unknown
+// to v4 are dropped, per-installer versions are substituted into the URL and options are written in
+// the flat form whenever they are the same for all architectures. The conversion fails if the x86
+// and x86_64 installers are of different kinds, since v4 cannot express that.
+func PackageToV4(pkg *Package) (*registry4.Package, error) {
+	var kind string
+	var first *Options
+	sameOptions := true
+	urls := map[string]string{}
+	checksums := map[string]string{}
+	options := map[string]interface{}{}
+
+	for _, arch := range registry4.Architectures {
+		installer, ok := pkg.Installers[arch]
+		if !ok || installer == nil {
+			continue
+		}
+
+		if kind == "" {
+			kind = installer.Kind
+			first = installer.Options
+		} else {
+			if kind != installer.Kind {
+				return nil, fmt.Errorf("installers are of different kinds (%v and %v)", kind, installer.Kind)
+			}
+
+			sameOptions = sameOptions && reflect.DeepEqual(first, installer.Options)
+		}
+
+		if installer.Checksum != "" {
+			checksums[arch] = installer.Checksum
+		}
+
+		urls[arch] = installer.URL
+		if pkg.VersionOf(installer) != pkg.Version {
+			urls[arch] = substituteVersion(installer.URL, pkg.VersionOf(installer))
+		}
+
+		raw, err := toRaw(installer.Options)
+		if err != nil {
+			return nil, err
+		}
+
+		if raw == nil {
+			raw = map[string]interface{}{}
+		}
+
+		options[arch] = raw
+	}
+
+	if kind == "" {
+		return nil, errors.New("package has no installer for either x86 or x86_64")
+	}
+
+	ret := &registry4.Package{
+		Aliases:    pkg.Aliases,
+		Checkver:   pkg.Checkver,
+		Deprecated: pkg.Deprecated,
+		Installer: &registry4.Installer{
+			Kind: kind,
+			X86:  urls[architecture.X86],
+		},
+		Languages:  pkg.Languages,
+		LintIgnore: pkg.LintIgnore,
+		SkipAudit:  pkg.SkipAudit,
+		Version:    pkg.Version,
+	}
+
+	// v4 falls back to the 32-bit installer on x86_64 by itself.
+	if urls[architecture.X86_64] != urls[architecture.X86] {
+		ret.Installer.X86_64 = urls[architecture.X86_64]
+	} else {
+		delete(checksums, architecture.X86_64)
+	}
+
+	if len(checksums) > 0 {
+		ret.Installer.Checksums = checksums
+	}
+
+	if sameOptions {
+		if first != nil {
+			raw, err := toRaw(first)
+			if err != nil {
+				return nil, err
+			}
+
+			ret.Installer.Options = raw
+		}
+	} else {
+		ret.Installer.Options = options
+	}
+
+	return ret, nil
+}
+
+// Verify checks that a v4 and a v5 registry describe exactly the same packages, as seen by the
+// installer on each v4 architecture. It is used to make sure a migration was lossless.
+func Verify(r4 *registry4.Registry, r5 *Registry) error {
+	if len(r4.Packages) != len(r5.Packages) {
+		return fmt.Errorf("expected %v packages but found %v", len(r4.Packages), len(r5.Packages))
+	}
+
+	for _, name := range r4.SortedPackageNames() {
+		p4 := r4.Packages[name]
+
+		p5, ok := r5.Packages[name]
+		if !ok {
+			return fmt.Errorf("%v: package is missing", name)
+		}
+
+		p5AsV4, err := PackageToV4(p5)
+		if err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+
+		if !samePackageMetadata(p4, p5AsV4) {
+			return fmt.Errorf("%v: package metadata differs", name)
+		}
+
+		if p4.Installer.Kind != p5AsV4.Installer.Kind {
+			return fmt.Errorf("%v: installer kind differs", name)
+		}
+
+		if len(p4.Installer.Checksums)+len(p5AsV4.Installer.Checksums) > 0 && !reflect.DeepEqual(p4.Installer.Checksums, p5AsV4.Installer.Checksums) {
+			return fmt.Errorf("%v: installer checksums differ", name)
+		}
+
+		for _, arch := range registry4.Architectures {
+			url4, options4, err4 := effectiveInstaller(p4, arch)
+			url5, options5, err5 := effectiveInstaller(p5AsV4, arch)
+
+			if (err4 == nil) != (err5 == nil) {
+				return fmt.Errorf("%v: availability of the %v installer differs", name, arch)
+			}
+
+			if url4 != url5 {
+				return fmt.Errorf("%v: %v installer URL differs (%v and %v)", name, arch, url4, url5)
+			}
+
+			if !reflect.DeepEqual(options4, options5) {
+				return fmt.Errorf("%v: %v installer options differ", name, arch)
+			}
+		}
+	}
+
+	return nil
+}
+
+// samePackageMetadata returns whether the given packages have the same metadata, i.e. everything
+// but the installer.
+func samePackageMetadata(a *registry4.Package, b *registry4.Package) bool {
+	aCopy := *a
+	bCopy := *b
+	aCopy.Installer = nil
+	bCopy.Installer = nil
+
+	return reflect.DeepEqual(aCopy, bCopy)
+}
+
+// effectiveInstaller returns the URL and options that would be used to install the given v4 package
+// on the given architecture.
+func effectiveInstaller(pkg *registry4.Package, arch string) (string, *registry4.Options, error) {
+	url := pkg.Installer.X86
+	if arch == architecture.X86_64 && strings2.IsNotEmpty(pkg.Installer.X86_64) {
+		url = pkg.Installer.X86_64
+	}
+
+	if strings2.IsEmpty(url) {
+		return "", nil, errors.New("no installer")
+	}
+
+	if len(pkg.Installer.Options) == 0 {
+		return url, nil, nil
+	}
+
+	options, err := pkg.Installer.OptionsForArch(arch)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return url, options, nil
+}
+
+// hasArchitectureSpecificOptions returns whether the v4 options of the given installer are keyed by
+// architecture.
+func hasArchitectureSpecificOptions(installer *registry4.Installer) bool {
+	for _, arch := range registry4.Architectures {
+		if _, ok := installer.Options[arch]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkRoundTrip makes sure that the given raw v4 options survive being converted to the typed
+// model, i.e. that they do not contain unknown keys.
+func checkRoundTrip(raw interface{}, options *Options) error {
+	rawNormalized, err := normalize(raw)
+	if err != nil {
+		return err
+	}
+
+	typedNormalized, err := toRaw(options)
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(rawNormalized, typedNormalized) {
+		return errors.New("options contain values that are not part of the model")
+	}
+
+	return nil
+}
+
+// toRaw converts typed options back to their untyped v4 representation.
+func toRaw(options *Options) (map[string]interface{}, error) {
+	normalized, err := normalize(options)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, _ := normalized.(map[string]interface{})
+	return ret, nil
+}
+
+// normalize passes the given value through JSON, so that values coming from different sources can be
+// compared with reflect.DeepEqual.
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret interface{}
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// substituteVersion replaces references to the "version" template variable in the given URL with
+// the given literal version.
+func substituteVersion(url string, version string) string {
+	for _, placeholder := range []string{"{{.version}}", "{{ .version }}"} {
+		url = strings.Replace(url, placeholder, version, -1)
+	}
+
+	return url
+}
@@ -0,0 +1,106 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry5
+
+import (
+	"sort"
+
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// FormatVersion is the value of the "version" field of v5 registries.
+const FormatVersion = 5
+
+// PackageMap maps package names to their associated metadata.
+type PackageMap map[string]*Package
+
+// Registry represents a package registry.
+type Registry struct {
+	Schema   string     `json:"$schema,omitempty"`
+	Version  int        `json:"version"`
+	Packages PackageMap `json:"packages"`
+}
+
+// SortedPackageNames returns the list of packages present in the registry, sorted alphabetically.
+func (r *Registry) SortedPackageNames() []string {
+	var keys []string
+
+	for k := range r.Packages {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Package represents a single package.
+type Package struct {
+	Version    string       `json:"version"`
+	Aliases    []string     `json:"aliases,omitempty"`
+	Checkver   *Checkver    `json:"checkver,omitempty"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	Languages  []string     `json:"languages,omitempty"`
+	LintIgnore []string     `json:"lintIgnore,omitempty"`
+	SkipAudit  bool         `json:"skipAudit,omitempty"`
+	Installers InstallerMap `json:"installers"`
+}
+
+// InstallerMap maps architectures to the installer to be used on them.
+type InstallerMap map[string]*Installer
+
+// SortedArchitectures returns the architectures for which the package has an installer, sorted
+// alphabetically.
+func (m InstallerMap) SortedArchitectures() []string {
+	var keys []string
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Installer contains information to fetch and execute the installer for a package on a single
+// architecture. Unlike v4, each architecture carries its own kind, URL and (already typed) options.
+type Installer struct {
+	Kind     string   `json:"kind"`
+	URL      string   `json:"url"`
+	Checksum string   `json:"checksum,omitempty"` // SHA-256 of the installer.
+	Version  string   `json:"version,omitempty"`  // Overrides Package.Version for this installer only.
+	Options  *Options `json:"options,omitempty"`
+}
+
+// VersionOf returns the version of the given installer, taking overrides into account.
+func (p *Package) VersionOf(installer *Installer) string {
+	if installer.Version != "" {
+		return installer.Version
+	}
+
+	return p.Version
+}
+
+// Checkver describes how to find the latest upstream version of a package. It is unchanged from v4.
+type Checkver = registry4.Checkver
+
+// Deprecation marks a package as deprecated. It is unchanged from v4.
+type Deprecation = registry4.Deprecation
+
+// Options are the options that can be used to customise the install process of a package. They did
+// not change between v4 and v5, only the way they are attached to a package did.
+type Options = registry4.Options
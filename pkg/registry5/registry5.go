@@ -0,0 +1,216 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry5
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/just-install/just-install/internal/atomicfile"
+	"github.com/just-install/just-install/pkg/architecture"
+	"github.com/just-install/just-install/pkg/dataformat"
+	"github.com/just-install/just-install/pkg/registry4"
+	"github.com/just-install/just-install/pkg/strings2"
+)
+
+// schemaVersionRegexp extracts the format version from a "$schema" URL such as
+// "https://example.com/just-install-v5.schema.json".
+var schemaVersionRegexp = regexp.MustCompile(`v(\d+)[^/]*$`)
+
+// DetectVersion returns the format version of the given registry document. The "version" field is
+// looked at first, falling back to the "$schema" URL when it is missing.
+func DetectVersion(b []byte) (int, error) {
+	var header struct {
+		Schema  string `json:"$schema"`
+		Version int    `json:"version"`
+	}
+
+	if err := json.Unmarshal(b, &header); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field == "" {
+			return 0, errors.New("document is not a registry")
+		}
+
+		return 0, err
+	}
+
+	if header.Version != 0 {
+		return header.Version, nil
+	}
+
+	if match := schemaVersionRegexp.FindStringSubmatch(header.Schema); match != nil {
+		return strconv.Atoi(match[1])
+	}
+
+	return 0, errors.New("could not detect registry version: both \"version\" and \"$schema\" are missing")
+}
+
+// Load loads a v5 registry file at the given path. The file can be in any of the formats supported
+// by the dataformat package, detected from its extension or contents.
+func Load(path string) (*Registry, error) {
+	b, err := readJSON(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(b)
+}
+
+// LoadCompatible loads either a v4 or a v5 registry file at the given path and returns it in the v4
+// model, for the benefit of code that has not been ported to v5 yet. Packages of a v5 registry that
+// cannot be expressed in v4 are skipped with a warning.
+func LoadCompatible(path string) (*registry4.Registry, error) {
+	b, err := readJSON(path)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := DetectVersion(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case registry4.FormatVersion:
+		return registry4.Load(path)
+	case FormatVersion:
+		r, err := parse(b)
+		if err != nil {
+			return nil, err
+		}
+
+		ret := &registry4.Registry{
+			Packages: registry4.PackageMap{},
+			Schema:   r.Schema,
+			Version:  registry4.FormatVersion,
+		}
+
+		for _, name := range r.SortedPackageNames() {
+			pkg, err := PackageToV4(r.Packages[name])
+			if err != nil {
+				log.Printf("WARNING: skipping package %v: %v", name, err)
+				continue
+			}
+
+			ret.Packages[name] = pkg
+		}
+
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("unsupported registry version: %v", version)
+	}
+}
+
+// Marshal encodes the registry in the given format, canonically (see dataformat.Marshal).
+func (r *Registry) Marshal(format dataformat.Format) ([]byte, error) {
+	return dataformat.Marshal(r, format)
+}
+
+// Save writes the registry to the given path, in the format matching its extension (JSON if
+// unknown). The file is replaced atomically.
+func (r *Registry) Save(path string) error {
+	format, ok := dataformat.FromExtension(path)
+	if !ok {
+		format = dataformat.JSON
+	}
+
+	return r.SaveAs(path, format)
+}
+
+// SaveAs is the same as Save, in the given format whatever the extension of the file.
+func (r *Registry) SaveAs(path string, format dataformat.Format) error {
+	b, err := r.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(path, b, 0644)
+}
+
+// Validate checks that the given registry is well-formed.
+func Validate(r *Registry) error {
+	if r.Version != FormatVersion {
+		return fmt.Errorf("expected registry version %v but found %v", FormatVersion, r.Version)
+	}
+
+	for _, name := range r.SortedPackageNames() {
+		pkg := r.Packages[name]
+		if pkg == nil {
+			return fmt.Errorf("%v: package entry is empty", name)
+		}
+
+		if len(pkg.Installers) == 0 {
+			return fmt.Errorf("%v: package has no installers", name)
+		}
+
+		for _, arch := range pkg.Installers.SortedArchitectures() {
+			installer := pkg.Installers[arch]
+
+			if !architecture.IsValid(arch) {
+				return fmt.Errorf("%v: unknown architecture: %v", name, arch)
+			}
+
+			if installer == nil {
+				return fmt.Errorf("%v: installer for %v is empty", name, arch)
+			}
+
+			if strings2.IsEmpty(installer.Kind) {
+				return fmt.Errorf("%v: installer for %v is missing \"kind\"", name, arch)
+			}
+
+			if strings2.IsEmpty(installer.URL) {
+				return fmt.Errorf("%v: installer for %v is missing \"url\"", name, arch)
+			}
+
+			if installer.Options != nil {
+				if err := installer.Options.Validate(); err != nil {
+					return fmt.Errorf("%v: installer for %v: %w", name, arch, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// readJSON reads the registry file at the given path and converts it to JSON.
+func readJSON(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataformat.ToJSON(b, dataformat.Detect(path, b))
+}
+
+// parse decodes and validates a v5 registry document.
+func parse(b []byte) (*Registry, error) {
+	ret := &Registry{}
+	if err := json.Unmarshal(b, ret); err != nil {
+		return nil, err
+	}
+
+	if err := Validate(ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,245 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package registry5
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/just-install/just-install/pkg/dataformat"
+	"github.com/just-install/just-install/pkg/registry4"
+)
+
+// v4Registry returns a v4 registry holding the given JSON package as "example".
+func v4Registry(t *testing.T, pkg string) *registry4.Registry {
+	t.Helper()
+
+	var ret registry4.Package
+	if err := json.Unmarshal([]byte(pkg), &ret); err != nil {
+		t.Fatal(err)
+	}
+
+	return &registry4.Registry{Version: registry4.FormatVersion, Packages: registry4.PackageMap{"example": &ret}}
+}
+
+func TestMigrationRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  string
+		want map[string]string // Architecture to URL of the migrated installers
+	}{
+		{
+			"x86 only",
+			`{"version": "1.0", "installer": {"kind": "nsis", "x86": "https://example.com/setup-{{.version}}.exe"}}`,
+			map[string]string{"x86": "https://example.com/setup-{{.version}}.exe"},
+		},
+		{
+			"both architectures with checksums",
+			`{"version": "1.0", "installer": {"kind": "msi", "x86": "https://example.com/x86.msi", "x86_64": "https://example.com/x64.msi", "checksums": {"x86": "aa", "x86_64": "bb"}}}`,
+			map[string]string{"x86": "https://example.com/x86.msi", "x86_64": "https://example.com/x64.msi"},
+		},
+		{
+			"flat options",
+			`{"version": "1.0", "installer": {"kind": "zip", "x86": "https://example.com/app.zip", "options": {"destination": "{{.PROGRAMFILES}}\\App", "shims": ["{{.PROGRAMFILES}}\\App\\app.exe"]}}}`,
+			map[string]string{"x86": "https://example.com/app.zip"},
+		},
+		{
+			"options per architecture",
+			`{"version": "1.0", "installer": {"kind": "zip", "x86": "https://example.com/x86.zip", "x86_64": "https://example.com/x64.zip", "options": {"x86": {"destination": "{{.PROGRAMFILES_X86}}\\App"}, "x86_64": {"destination": "{{.PROGRAMFILES}}\\App"}}}}`,
+			map[string]string{"x86": "https://example.com/x86.zip", "x86_64": "https://example.com/x64.zip"},
+		},
+		{
+			"32-bit installer with 64-bit options",
+			`{"version": "1.0", "installer": {"kind": "zip", "x86": "https://example.com/app.zip", "checksums": {"x86": "aa"}, "options": {"x86": {"destination": "{{.PROGRAMFILES_X86}}\\App"}, "x86_64": {"destination": "{{.PROGRAMFILES}}\\App"}}}}`,
+			map[string]string{"x86": "https://example.com/app.zip", "x86_64": "https://example.com/app.zip"},
+		},
+		{
+			"metadata",
+			`{"version": "1.0", "aliases": ["ex"], "languages": ["en", "de"], "lintIgnore": ["plain-http"], "skipAudit": true, "deprecated": {"replacement": "other"}, "installer": {"kind": "nsis", "x86": "http://example.com/setup-{{.lang}}.exe"}}`,
+			map[string]string{"x86": "http://example.com/setup-{{.lang}}.exe"},
+		},
+	}
+
+	for _, test := range tests {
+		r4 := v4Registry(t, test.pkg)
+
+		r5, err := FromV4(r4)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		got := map[string]string{}
+		for arch, installer := range r5.Packages["example"].Installers {
+			got[arch] = installer.URL
+		}
+
+		if len(got) != len(test.want) {
+			t.Errorf("%v: got installers %v, want %v", test.name, got, test.want)
+		}
+		for arch, url := range test.want {
+			if got[arch] != url {
+				t.Errorf("%v: got installers %v, want %v", test.name, got, test.want)
+			}
+		}
+
+		if err := Verify(r4, r5); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		}
+
+		// The migrated registry survives being saved and loaded again, in every format
+		for _, format := range []dataformat.Format{dataformat.JSON, dataformat.TOML, dataformat.YAML} {
+			path := filepath.Join(t.TempDir(), "registry"+format.Extension())
+			if err := r5.Save(path); err != nil {
+				t.Errorf("%v (%v): %v", test.name, format, err)
+				continue
+			}
+
+			loaded, err := Load(path)
+			if err != nil {
+				t.Errorf("%v (%v): %v", test.name, format, err)
+				continue
+			}
+
+			if err := Verify(r4, loaded); err != nil {
+				t.Errorf("%v (%v): %v", test.name, format, err)
+			}
+		}
+
+		// And converting it back yields an equivalent v4 registry
+		back, err := ToV4(r5)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if err := Verify(back, r5); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		}
+	}
+}
+
+func TestFromV4Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  string
+		want string
+	}{
+		{"no installer", `{"version": "1.0"}`, "missing \"installer\""},
+		{"no URL", `{"version": "1.0", "installer": {"kind": "nsis"}}`, "missing both"},
+		{"unknown option", `{"version": "1.0", "installer": {"kind": "nsis", "x86": "https://example.com/setup.exe", "options": {"destination": "x", "bogus": true}}}`, "not part of the model"},
+		{"options without installer", `{"version": "1.0", "installer": {"kind": "nsis", "x86_64": "https://example.com/setup.exe", "options": {"x86": {"destination": "x"}, "x86_64": {"destination": "y"}}}}`, "no installer for it"},
+	}
+
+	for _, test := range tests {
+		_, err := FromV4(v4Registry(t, test.pkg))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: got %v, want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestPackageToV4(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     string
+		want    string // Expected v4 installer as JSON
+		wantErr string
+	}{
+		{
+			"arm64 is dropped",
+			`{"version": "1.0", "installers": {"x86_64": {"kind": "msi", "url": "https://example.com/x64.msi"}, "arm64": {"kind": "msi", "url": "https://example.com/arm64.msi"}}}`,
+			`{"kind":"msi","x86_64":"https://example.com/x64.msi"}`,
+			"",
+		},
+		{
+			"installer version is substituted",
+			`{"version": "2.0", "installers": {"x86": {"kind": "nsis", "url": "https://example.com/{{.version}}/x86.exe", "version": "1.9"}, "x86_64": {"kind": "nsis", "url": "https://example.com/{{.version}}/x64.exe"}}}`,
+			`{"kind":"nsis","x86":"https://example.com/1.9/x86.exe","x86_64":"https://example.com/{{.version}}/x64.exe"}`,
+			"",
+		},
+		{
+			"same URL on both architectures",
+			`{"version": "1.0", "installers": {"x86": {"kind": "nsis", "url": "https://example.com/setup.exe", "checksum": "aa"}, "x86_64": {"kind": "nsis", "url": "https://example.com/setup.exe", "checksum": "aa"}}}`,
+			`{"checksums":{"x86":"aa"},"kind":"nsis","x86":"https://example.com/setup.exe"}`,
+			"",
+		},
+		{
+			"different kinds",
+			`{"version": "1.0", "installers": {"x86": {"kind": "nsis", "url": "https://example.com/x86.exe"}, "x86_64": {"kind": "msi", "url": "https://example.com/x64.msi"}}}`,
+			"",
+			"different kinds",
+		},
+		{
+			"arm64 only",
+			`{"version": "1.0", "installers": {"arm64": {"kind": "msi", "url": "https://example.com/arm64.msi"}}}`,
+			"",
+			"no installer",
+		},
+	}
+
+	for _, test := range tests {
+		var pkg Package
+		if err := json.Unmarshal([]byte(test.pkg), &pkg); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		got, err := PackageToV4(&pkg)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%v: got %v, want an error containing %q", test.name, err, test.wantErr)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		// Go through a map, whose keys are marshaled in order
+		var raw interface{}
+		b, _ := json.Marshal(got.Installer)
+		json.Unmarshal(b, &raw)
+
+		if gotJSON, _ := json.Marshal(raw); string(gotJSON) != test.want {
+			t.Errorf("%v: got %s, want %s", test.name, gotJSON, test.want)
+		}
+	}
+}
+
+func TestDetectVersion(t *testing.T) {
+	tests := []struct {
+		doc     string
+		want    int
+		wantErr bool
+	}{
+		{`{"version": 5, "packages": {}}`, 5, false},
+		{`{"version": 4}`, 4, false},
+		{`{"$schema": "https://just-install.github.io/registry/just-install-v4.schema.json"}`, 4, false},
+		{`{"$schema": "https://example.com/v5/registry.json", "version": 5}`, 5, false},
+		{`{"packages": {}}`, 0, true},
+		{`[]`, 0, true},
+		{`{"version": "5"}`, 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := DetectVersion([]byte(test.doc))
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("DetectVersion(%v) = %v, %v, want %v, error %v", test.doc, got, err, test.want, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !windows
+// +build !windows
+
+package shortcut
+
+import (
+	"path/filepath"
+
+	"github.com/just-install/just-install/pkg/paths"
+)
+
+// StartMenuDir returns a stand-in for the Start Menu below the data directory, since there is no
+// Start Menu outside of Windows.
+func StartMenuDir(user bool) (string, error) {
+	return fakeDir(user, "Start Menu")
+}
+
+// DesktopDir returns a stand-in for the desktop below the data directory.
+func DesktopDir(user bool) (string, error) {
+	return fakeDir(user, "Desktop")
+}
+
+func fakeDir(user bool, name string) (string, error) {
+	dir, err := paths.DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	scope := "machine"
+	if user {
+		scope = "user"
+	}
+
+	return filepath.Join(dir, "shortcuts", scope, name), nil
+}
@@ -0,0 +1,59 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shortcut
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// StartMenuDir returns the Programs folder of the Start Menu of all users or, if user is true, of
+// the current user.
+func StartMenuDir(user bool) (string, error) {
+	base, err := knownDir(user, "ProgramData", "APPDATA")
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(base, "Microsoft", "Windows", "Start Menu", "Programs"), nil
+}
+
+// DesktopDir returns the desktop of all users or, if user is true, of the current user.
+func DesktopDir(user bool) (string, error) {
+	base, err := knownDir(user, "PUBLIC", "USERPROFILE")
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(base, "Desktop"), nil
+}
+
+// knownDir returns the value of the machine or user environment variable naming the base directory
+// of a shell folder.
+func knownDir(user bool, machineVariable string, userVariable string) (string, error) {
+	variable := machineVariable
+	if user {
+		variable = userVariable
+	}
+
+	ret := os.Getenv(variable)
+	if ret == "" {
+		return "", errors.New("%" + variable + "% is not set")
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,128 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shortcut
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/gotopkg/mslnk/pkg/mslnk"
+
+	"github.com/just-install/just-install/internal/atomicfile"
+)
+
+// Shortcut describes a shortcut to a program.
+type Shortcut struct {
+	Target           string // Absolute path of the program
+	Arguments        string // Command line arguments, already quoted
+	WorkingDirectory string
+	Icon             string // File the icon is taken from, the target's own icon if empty
+	IconIndex        int    // Index of the icon in Icon
+	Description      string // Tooltip of the shortcut
+}
+
+// Bytes returns the contents of a .lnk file for the shortcut.
+func (s *Shortcut) Bytes() ([]byte, error) {
+	target := strings.TrimSpace(s.Target)
+	if target == "" {
+		return nil, errors.New("shortcut target is empty")
+	}
+
+	// Same target ID list as mslnk.LinkFile, which doesn't support any of the other fields
+	drive := "C:\\"
+	if len(target) >= 3 && target[1] == ':' && target[2] == '\\' {
+		drive = target[:3]
+		target = target[3:]
+	}
+
+	link := mslnk.ShellLink{
+		ShellLinkHeader: mslnk.Header(),
+		LinkTargetIDList: mslnk.LinkTargetIDList{
+			ItemIDList: []mslnk.ItemID{
+				mslnk.ItemIDCLSID(mslnk.ItemIDMagic["MY_COMPUTER"]),
+				mslnk.ItemIDDrive(drive),
+				mslnk.ItemIDFile(target),
+			},
+		},
+		StringData: mslnk.StringData{},
+	}
+
+	link.LinkTargetIDList.Size()
+	link.ShellLinkHeader.LinkFlags["HasLinkTargetIDList"] = true
+	link.ShellLinkHeader.LinkFlags["ForceNoLinkInfo"] = true
+	link.ShellLinkHeader.LinkFlags["IsUnicode"] = true
+	link.ShellLinkHeader.FileAttributes["FILE_ATTRIBUTE_NORMAL"] = true
+
+	for key, value := range map[string]string{
+		"NameString":           s.Description,
+		"WorkingDir":           s.WorkingDirectory,
+		"CommandLineArguments": s.Arguments,
+		"IconLocation":         s.Icon,
+	} {
+		if value == "" {
+			continue
+		}
+
+		data, err := stringData(value)
+		if err != nil {
+			return nil, err
+		}
+
+		link.StringData[key] = data
+	}
+
+	binary.LittleEndian.PutUint32(link.ShellLinkHeader.Data.IconIndex[:], uint32(int32(s.IconIndex)))
+	link.StringData.Update(&link.ShellLinkHeader)
+
+	// The extra data section ends with a terminal block, even if it's empty
+	link.ExtraData = make([]byte, 4)
+
+	return link.Bytes(), nil
+}
+
+// Save writes the shortcut to the given .lnk file, creating its directory if needed.
+func (s *Shortcut) Save(path string) error {
+	data, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(path, data, 0644)
+}
+
+// stringData encodes a string of the StringData section of unicode .lnk files: its length in
+// characters followed by its UTF-16LE encoding.
+func stringData(s string) ([]byte, error) {
+	chars := utf16.Encode([]rune(s))
+	if len(chars) > 0xffff {
+		return nil, errors.New("shortcut string is too long")
+	}
+
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, uint16(len(chars)))
+	binary.Write(&buffer, binary.LittleEndian, chars)
+
+	return buffer.Bytes(), nil
+}
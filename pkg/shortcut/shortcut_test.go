@@ -0,0 +1,203 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shortcut
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// Link flags of the shell link header, see [MS-SHLLINK] 2.1.1.
+const (
+	hasLinkTargetIDList = 1 << 0
+	hasLinkInfo         = 1 << 1
+	hasName             = 1 << 2
+	hasRelativePath     = 1 << 3
+	hasWorkingDir       = 1 << 4
+	hasArguments        = 1 << 5
+	hasIconLocation     = 1 << 6
+	isUnicode           = 1 << 7
+	forceNoLinkInfo     = 1 << 8
+)
+
+// parsedLink is what parseLink reads back from a .lnk file.
+type parsedLink struct {
+	Flags       uint32
+	IconIndex   int32
+	Description string
+	WorkingDir  string
+	Arguments   string
+	Icon        string
+}
+
+// parseLink parses the parts of a .lnk file written by Bytes.
+func parseLink(b []byte) (*parsedLink, error) {
+	if len(b) < 76 || binary.LittleEndian.Uint32(b) != 76 {
+		return nil, errors.New("invalid header")
+	}
+
+	ret := &parsedLink{
+		Flags:     binary.LittleEndian.Uint32(b[20:]),
+		IconIndex: int32(binary.LittleEndian.Uint32(b[56:])),
+	}
+	b = b[76:]
+
+	if ret.Flags&hasLinkTargetIDList != 0 {
+		if len(b) < 2 {
+			return nil, errors.New("truncated target ID list")
+		}
+
+		size := int(binary.LittleEndian.Uint16(b))
+		if len(b) < 2+size {
+			return nil, errors.New("truncated target ID list")
+		}
+		b = b[2+size:]
+	}
+
+	// String data comes in a fixed order, each present only if its flag is set
+	for _, s := range []struct {
+		flag  uint32
+		value *string
+	}{
+		{hasName, &ret.Description},
+		{hasRelativePath, new(string)},
+		{hasWorkingDir, &ret.WorkingDir},
+		{hasArguments, &ret.Arguments},
+		{hasIconLocation, &ret.Icon},
+	} {
+		if ret.Flags&s.flag == 0 {
+			continue
+		}
+
+		if len(b) < 2 {
+			return nil, errors.New("truncated string data")
+		}
+
+		n := int(binary.LittleEndian.Uint16(b))
+		if len(b) < 2+2*n {
+			return nil, errors.New("truncated string data")
+		}
+
+		chars := make([]uint16, n)
+		for i := range chars {
+			chars[i] = binary.LittleEndian.Uint16(b[2+2*i:])
+		}
+		*s.value = string(utf16.Decode(chars))
+		b = b[2+2*n:]
+	}
+
+	if len(b) != 4 || binary.LittleEndian.Uint32(b) != 0 {
+		return nil, errors.New("missing terminal block")
+	}
+
+	return ret, nil
+}
+
+func TestBytes(t *testing.T) {
+	const baseFlags = hasLinkTargetIDList | isUnicode | forceNoLinkInfo
+
+	tests := []struct {
+		name     string
+		shortcut Shortcut
+		want     parsedLink
+	}{
+		{
+			"target only",
+			Shortcut{Target: "C:\\Program Files\\Example\\example.exe"},
+			parsedLink{Flags: baseFlags},
+		},
+		{
+			"all fields",
+			Shortcut{
+				Target:           "D:\\Tools\\example.exe",
+				Arguments:        "--profile \"my profile\"",
+				WorkingDirectory: "D:\\Tools",
+				Icon:             "D:\\Tools\\icons.dll",
+				IconIndex:        3,
+				Description:      "Example tool",
+			},
+			parsedLink{
+				Flags:       baseFlags | hasName | hasWorkingDir | hasArguments | hasIconLocation,
+				IconIndex:   3,
+				Description: "Example tool",
+				WorkingDir:  "D:\\Tools",
+				Arguments:   "--profile \"my profile\"",
+				Icon:        "D:\\Tools\\icons.dll",
+			},
+		},
+		{
+			"non-ASCII strings and negative icon index",
+			Shortcut{Target: "C:\\Outils\\exemple.exe", Icon: "C:\\Outils\\exemple.exe", IconIndex: -101, Description: "Éditeur 📝"},
+			parsedLink{Flags: baseFlags | hasName | hasIconLocation, IconIndex: -101, Description: "Éditeur 📝", Icon: "C:\\Outils\\exemple.exe"},
+		},
+	}
+
+	for _, test := range tests {
+		b, err := test.shortcut.Bytes()
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		got, err := parseLink(b)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if got.Flags&hasLinkInfo != 0 {
+			t.Errorf("%v: link has a LinkInfo structure", test.name)
+		}
+
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("%v: got %+v, want %+v", test.name, *got, test.want)
+		}
+	}
+}
+
+func TestBytesEmptyTarget(t *testing.T) {
+	if _, err := (&Shortcut{Target: " "}).Bytes(); err == nil {
+		t.Error("shortcut without target was accepted")
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Start Menu", "Example", "Example.lnk")
+	s := &Shortcut{Target: "C:\\Example\\example.exe", Description: "Example"}
+
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := s.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Error("saved shortcut differs from Bytes")
+	}
+}
@@ -26,3 +26,47 @@ func IsEmpty(s string) bool {
 func IsNotEmpty(s string) bool {
 	return len(strings.TrimSpace(s)) > 0
 }
+
+// EditDistance returns the Levenshtein distance between the given strings, i.e. the minimum number
+// of single-character insertions, deletions and substitutions needed to turn one into the other.
+func EditDistance(a string, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+// minInt returns the smallest of the given values.
+func minInt(values ...int) int {
+	ret := values[0]
+
+	for _, v := range values[1:] {
+		if v < ret {
+			ret = v
+		}
+	}
+
+	return ret
+}
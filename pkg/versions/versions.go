@@ -0,0 +1,123 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package versions
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Compare compares two version strings, returning -1, 0 or 1 if a is respectively older than, the
+// same as or newer than b. Versions are split into runs of digits and runs of other characters;
+// digits are compared numerically, everything else lexically (case-insensitively). A leading "v" is
+// ignored, so that "v1.10" is newer than "1.9". Missing trailing numbers count as 0, so that "1.0"
+// is the same as "1.0.0", while a version that goes on with letters is a prerelease, so that
+// "1.0-beta" is older than "1.0".
+func Compare(a string, b string) int {
+	aParts := split(a)
+	bParts := split(b)
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var c int
+		switch {
+		case i >= len(aParts):
+			c = -compareMissing(bParts[i])
+		case i >= len(bParts):
+			c = compareMissing(aParts[i])
+		default:
+			c = compareParts(aParts[i], bParts[i])
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// split splits a version string into runs of digits and runs of letters, dropping separators.
+func split(v string) []string {
+	v = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(v)), "v")
+
+	var ret []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			ret = append(ret, string(current))
+			current = nil
+		}
+	}
+
+	for _, r := range v {
+		switch {
+		case unicode.IsDigit(r):
+			if len(current) > 0 && !unicode.IsDigit(current[0]) {
+				flush()
+			}
+			current = append(current, r)
+		case unicode.IsLetter(r):
+			if len(current) > 0 && !unicode.IsLetter(current[0]) {
+				flush()
+			}
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+
+	flush()
+
+	return ret
+}
+
+// compareParts compares two parts of a version string.
+func compareParts(a string, b string) int {
+	aNumber, aErr := strconv.ParseUint(a, 10, 64)
+	bNumber, bErr := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		if aNumber < bNumber {
+			return -1
+		} else if aNumber > bNumber {
+			return 1
+		}
+
+		return 0
+	case aErr == nil:
+		return 1 // "1.0.1" is newer than "1.0.beta"
+	case bErr == nil:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// compareMissing compares a part of a version string with the same part of a shorter version.
+func compareMissing(part string) int {
+	number, err := strconv.ParseUint(part, 10, 64)
+	switch {
+	case err != nil:
+		return -1 // "1.0-beta" is older than "1.0"
+	case number > 0:
+		return 1
+	default:
+		return 0
+	}
+}
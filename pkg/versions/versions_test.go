@@ -0,0 +1,58 @@
+// just-install - The simple package installer for Windows
+// Copyright (C) 2020 just-install authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package versions
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.0.0.0", "1", 0},
+		{"v1.2", "1.2", 0},
+		{"1.0-RC1", "1.0-rc1", 0},
+		{"1.9", "1.10", -1},
+		{"v1.10", "1.9", 1},
+		{"1.0.1", "1.0", 1},
+		{"1.0", "1.0.1", -1},
+		{"2", "1.99.99", 1},
+		{"1.0-beta", "1.0", -1},
+		{"1.0", "1.0-beta", 1},
+		{"1.0.0-beta", "1.0", -1},
+		{"1.0b2", "1.0", -1},
+		{"1.0-alpha", "1.0-beta", -1},
+		{"1.0-beta2", "1.0-beta10", -1},
+		{"1.0-beta", "1.0.1", -1},
+		{"1.0.1", "1.0.beta", 1},
+		{"2020.05.17", "2020.5.17", 0},
+		{"", "", 0},
+		{"", "1.0", -1},
+	}
+
+	for _, test := range tests {
+		if got := Compare(test.a, test.b); got != test.want {
+			t.Errorf("Compare(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+
+		if got := Compare(test.b, test.a); got != -test.want {
+			t.Errorf("Compare(%q, %q) = %v, want %v", test.b, test.a, got, -test.want)
+		}
+	}
+}